@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+)
+
+const (
+	maxBatchSize = 100
+	batchWorkers = 4
+)
+
+// handleBatch processes a JSON-RPC 2.0 batch. Elements run concurrently on a
+// bounded worker pool; responses come back in request order with
+// notifications left out, and a batch of only notifications gets 202.
+func (s *MCPServer) handleBatch(w http.ResponseWriter, r *http.Request, body []byte) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(body, &elements); err != nil {
+		log.Printf("Invalid JSON-RPC batch: %v", err)
+		writeParseError(w)
+		return
+	}
+
+	if len(elements) == 0 || len(elements) > maxBatchSize {
+		stream := newResponseStream(w, r)
+		stream.finish(s.sendError(nil, -32600, "Invalid Request", "batch must contain between 1 and 100 messages"))
+		return
+	}
+
+	// Decode up front so the session requirement is known before running anything
+	requests := make([]*JSONRPCRequest, len(elements))
+	needsSession := false
+	for i, element := range elements {
+		var req JSONRPCRequest
+		if err := json.Unmarshal(element, &req); err != nil {
+			continue
+		}
+		requests[i] = &req
+		if req.Method != "ping" {
+			needsSession = true
+		}
+	}
+
+	scope, ok := s.resolveScope(w, r, needsSession)
+	if !ok {
+		return
+	}
+
+	stream := newResponseStream(w, r)
+	scope.notify = stream.notify
+	ctx := withScope(r.Context(), scope)
+
+	log.Printf("Processing JSON-RPC batch of %d messages", len(requests))
+
+	responses := make([]*JSONRPCResponse, len(requests))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for n := 0; n < batchWorkers && n < len(requests); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				req := requests[i]
+				switch {
+				case req == nil:
+					resp := s.sendError(nil, -32600, "Invalid Request", nil)
+					responses[i] = &resp
+				case req.Method == "initialize":
+					// initialize opens the session and must travel alone
+					if !isNotification(*req) {
+						resp := s.sendError(req.ID, -32600, "Invalid Request", "initialize cannot be part of a batch")
+						responses[i] = &resp
+					}
+				case isNotification(*req):
+					s.handleRequest(ctx, *req)
+				default:
+					resp := s.handleRequest(ctx, *req)
+					responses[i] = &resp
+				}
+			}
+		}()
+	}
+
+	for i := range requests {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	results := make([]JSONRPCResponse, 0, len(responses))
+	for _, resp := range responses {
+		if resp != nil {
+			results = append(results, *resp)
+		}
+	}
+
+	if len(results) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	stream.finish(results)
+}
@@ -3,6 +3,6 @@ module indian-store-mcp-server
 go 1.24.0
 
 require (
-	github.com/lib/pq v1.10.9 // indirect
-	golang.org/x/crypto v0.46.0 // indirect
+	github.com/lib/pq v1.10.9
+	golang.org/x/crypto v0.46.0
 )
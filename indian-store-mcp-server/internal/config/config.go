@@ -2,14 +2,17 @@ package config
 
 import (
 	"log"
+	"net"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
 	// Server Configuration
-	Host string
-	Port string
+	Host           string
+	Port           string
+	TrustedProxies []string // Proxy IPs or CIDRs whose X-Forwarded-For is believed; empty trusts none
 
 	// Ory Configuration
 	OryURL              string // Base URL for Ory (e.g., https://your-project.projects.oryapis.com)
@@ -21,33 +24,133 @@ type Config struct {
 	OryScopes           string
 	OryIntrospectionURL string // URL for token introspection
 	OryUserInfoURL      string // URL for user info
+	DiscoveryCacheTTL   int    // Seconds Hydra's openid-configuration is cached
+
+	// Dynamic client registration
+	RedirectAllowedHosts  []string // Hosts allowed in redirect_uris ("*.example.com" for subdomains, "*" for any)
+	RedirectAllowLoopback bool     // Allow http(s) redirects to localhost/127.0.0.1/[::1] for native clients
+
+	RegistrationRequireInitialToken bool // Require an admin-issued initial access token to register
+	RegistrationRateLimitWindow     int  // Seconds per registration rate limit window
+	RegistrationRateLimitPerIP      int  // Registrations per client IP per window; 0 disables
+	RegistrationRateLimitGlobal     int  // Registrations per window across all callers; 0 disables
+	RegistrationMaxClientsPerName   int  // Registered clients sharing one client_name; 0 disables
+
+	// Reaper for abandoned dynamically registered clients
+	ClientReaperEnabled  bool   // Run the reaper in the background
+	ClientReaperInterval int    // Seconds between reaper runs
+	ClientReaperMaxIdle  int    // Seconds without a token issued before a client is reaped
+	ClientReaperDryRun   bool   // Only log what the background reaper would delete
+	TokenHookSecret      string // Bearer token Hydra sends to /oauth/token-hook; empty disables the hook
+
+	// Login sessions (login, consent and account pages)
+	SessionStore           string // "postgres" (shared by replicas) or "memory"
+	SessionLifetime        int    // Seconds a login session lasts
+	SessionCleanupInterval int    // Seconds between sweeps of expired sessions
+
+	// Consent
+	FirstPartyClients  []string // Client IDs granted consent without showing the consent page
+	ConsentRememberFor int      // Seconds a remembered consent lasts; 0 means until revoked
+
+	// Login brute-force protection
+	LoginMaxFailures     int // Failed logins per account before it is locked; 0 disables
+	LoginIPMaxFailures   int // Failed logins per client IP before it is locked; 0 disables
+	LoginFailureWindow   int // Seconds without failures after which the count starts over
+	LoginLockoutBase     int // Seconds of the first lockout; doubles with each further failure
+	LoginLockoutMax      int // Longest lockout in seconds
+	SecurityLogRetention int // Seconds security log entries are kept
+
+	// Two-factor authentication
+	MFARequiredRoles []string // User roles that must use 2FA; others may opt in
+	MFAIssuer        string   // Name shown in authenticator apps
+
+	// Admin API
+	AdminAPIToken string // Bearer token for /admin/* endpoints; empty disables them
+
+	// Access token validation
+	TokenValidation string // "introspection", "jwt" or "auto" (JWTs locally, opaque tokens via introspection)
+	TokenIssuer     string // Expected iss of JWT access tokens (Hydra urls.self.issuer)
+	TokenAudience   string // Expected aud of JWT access tokens (required for jwt and auto)
+	JWKSCacheTTL    int    // Seconds before Hydra's signing keys are refetched
+
+	// Introspection cache
+	IntrospectionCacheTTL         int // Max seconds an active token's result is reused; 0 disables the cache
+	IntrospectionCacheNegativeTTL int // Seconds an inactive token's result is reused
+	IntrospectionCacheSize        int // Max cached tokens before LRU eviction
 
 	// JWT Configuration (for session management if needed)
-	JWTSecret          string
+	JWTSecret            string
 	AccessTokenLifetime  int
 	RefreshTokenLifetime int
 
 	// Database Configuration
 	DatabaseURL string
+
+	// MCP Configuration
+	MCPSessionIdleTimeout int // Seconds before an idle MCP session is dropped
+	CatalogPollInterval   int // Seconds between checks for store catalog changes
 }
 
 func Load() *Config {
 	cfg := &Config{
-		Host:                 getEnv("HOST", "0.0.0.0"),
-		Port:                 getEnv("PORT", "8080"),
-		OryURL:               getEnv("ORY_URL", ""),
-		OryInternalURL:       getEnv("ORY_INTERNAL_URL", ""),
-		OryAdminURL:          getEnv("ORY_ADMIN_URL", ""),
-		OryClientID:          getEnv("ORY_CLIENT_ID", ""),
-		OryClientSecret:      getEnv("ORY_CLIENT_SECRET", ""),
-		OryCallbackURL:       getEnv("ORY_CALLBACK_URL", "http://localhost:8080/oauth/callback"),
-		OryScopes:            getEnv("ORY_SCOPES", "openid offline_access"),
-		OryIntrospectionURL:  getEnv("ORY_INTROSPECTION_URL", ""),
-		OryUserInfoURL:       getEnv("ORY_USERINFO_URL", ""),
-		JWTSecret:            getEnv("JWT_SECRET", "default-secret-change-in-production"),
-		AccessTokenLifetime:  getEnvAsInt("ACCESS_TOKEN_LIFETIME", 3600),
-		RefreshTokenLifetime: getEnvAsInt("REFRESH_TOKEN_LIFETIME", 604800),
-		DatabaseURL:          getEnv("DATABASE_URL", ""),
+		Host:                          getEnv("HOST", "0.0.0.0"),
+		Port:                          getEnv("PORT", "8080"),
+		TrustedProxies:                getEnvAsList("TRUSTED_PROXIES", nil),
+		OryURL:                        getEnv("ORY_URL", ""),
+		OryInternalURL:                getEnv("ORY_INTERNAL_URL", ""),
+		OryAdminURL:                   getEnv("ORY_ADMIN_URL", ""),
+		OryClientID:                   getEnv("ORY_CLIENT_ID", ""),
+		OryClientSecret:               getEnv("ORY_CLIENT_SECRET", ""),
+		OryCallbackURL:                getEnv("ORY_CALLBACK_URL", "http://localhost:8080/oauth/callback"),
+		OryScopes:                     getEnv("ORY_SCOPES", "openid offline_access"),
+		OryIntrospectionURL:           getEnv("ORY_INTROSPECTION_URL", ""),
+		OryUserInfoURL:                getEnv("ORY_USERINFO_URL", ""),
+		DiscoveryCacheTTL:             getEnvAsInt("DISCOVERY_CACHE_TTL", 300),
+		RedirectAllowedHosts:          getEnvAsList("REDIRECT_ALLOWED_HOSTS", []string{"chatgpt.com", "chat.openai.com", "claude.ai", "claude.com"}),
+		RedirectAllowLoopback:         getEnvAsBool("REDIRECT_ALLOW_LOOPBACK", true),
+		TokenValidation:               getEnv("TOKEN_VALIDATION", "introspection"),
+		TokenIssuer:                   getEnv("TOKEN_ISSUER", ""),
+		TokenAudience:                 getEnv("TOKEN_AUDIENCE", ""),
+		JWKSCacheTTL:                  getEnvAsInt("JWKS_CACHE_TTL", 3600),
+		IntrospectionCacheTTL:         getEnvAsInt("INTROSPECTION_CACHE_TTL", 60),
+		IntrospectionCacheNegativeTTL: getEnvAsInt("INTROSPECTION_CACHE_NEGATIVE_TTL", 10),
+		IntrospectionCacheSize:        getEnvAsInt("INTROSPECTION_CACHE_SIZE", 10000),
+		JWTSecret:                     getEnv("JWT_SECRET", "default-secret-change-in-production"),
+		AccessTokenLifetime:           getEnvAsInt("ACCESS_TOKEN_LIFETIME", 3600),
+		RefreshTokenLifetime:          getEnvAsInt("REFRESH_TOKEN_LIFETIME", 604800),
+		DatabaseURL:                   getEnv("DATABASE_URL", ""),
+		MCPSessionIdleTimeout:         getEnvAsInt("MCP_SESSION_IDLE_TIMEOUT", 1800),
+		CatalogPollInterval:           getEnvAsInt("CATALOG_POLL_INTERVAL", 30),
+
+		RegistrationRequireInitialToken: getEnvAsBool("REGISTRATION_REQUIRE_INITIAL_TOKEN", false),
+		RegistrationRateLimitWindow:     getEnvAsInt("REGISTRATION_RATE_LIMIT_WINDOW", 3600),
+		RegistrationRateLimitPerIP:      getEnvAsInt("REGISTRATION_RATE_LIMIT_PER_IP", 20),
+		RegistrationRateLimitGlobal:     getEnvAsInt("REGISTRATION_RATE_LIMIT_GLOBAL", 500),
+		RegistrationMaxClientsPerName:   getEnvAsInt("REGISTRATION_MAX_CLIENTS_PER_NAME", 100),
+		AdminAPIToken:                   getEnv("ADMIN_API_TOKEN", ""),
+
+		ClientReaperEnabled:  getEnvAsBool("CLIENT_REAPER_ENABLED", false),
+		ClientReaperInterval: getEnvAsInt("CLIENT_REAPER_INTERVAL", 3600),
+		ClientReaperMaxIdle:  getEnvAsInt("CLIENT_REAPER_MAX_IDLE", 2592000),
+		ClientReaperDryRun:   getEnvAsBool("CLIENT_REAPER_DRY_RUN", true),
+		TokenHookSecret:      getEnv("TOKEN_HOOK_SECRET", ""),
+
+		SessionStore:           getEnv("SESSION_STORE", "postgres"),
+		SessionLifetime:        getEnvAsInt("SESSION_LIFETIME", 86400),
+		SessionCleanupInterval: getEnvAsInt("SESSION_CLEANUP_INTERVAL", 600),
+
+		FirstPartyClients:  getEnvAsList("FIRST_PARTY_CLIENTS", nil),
+		ConsentRememberFor: getEnvAsInt("CONSENT_REMEMBER_FOR", 2592000),
+
+		LoginMaxFailures:     getEnvAsInt("LOGIN_MAX_FAILURES", 5),
+		LoginIPMaxFailures:   getEnvAsInt("LOGIN_IP_MAX_FAILURES", 20),
+		LoginFailureWindow:   getEnvAsInt("LOGIN_FAILURE_WINDOW", 900),
+		LoginLockoutBase:     getEnvAsInt("LOGIN_LOCKOUT_BASE", 60),
+		LoginLockoutMax:      getEnvAsInt("LOGIN_LOCKOUT_MAX", 3600),
+		SecurityLogRetention: getEnvAsInt("SECURITY_LOG_RETENTION", 7776000),
+
+		MFARequiredRoles: getEnvAsList("MFA_REQUIRED_ROLES", nil),
+		MFAIssuer:        getEnv("MFA_ISSUER", "Indian Store MCP"),
 	}
 
 	// Validate required fields
@@ -57,6 +160,56 @@ func Load() *Config {
 	if cfg.DatabaseURL == "" {
 		log.Fatal("DATABASE_URL is required")
 	}
+	for _, proxy := range cfg.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			log.Fatalf("TRUSTED_PROXIES entry %q is not an IP address or CIDR", proxy)
+		}
+	}
+	switch cfg.TokenValidation {
+	case "introspection":
+	case "jwt", "auto":
+		if cfg.TokenIssuer == "" {
+			log.Fatalf("TOKEN_ISSUER is required when TOKEN_VALIDATION is %s", cfg.TokenValidation)
+		}
+		if cfg.TokenAudience == "" {
+			log.Fatalf("TOKEN_AUDIENCE is required when TOKEN_VALIDATION is %s", cfg.TokenValidation)
+		}
+	default:
+		log.Fatalf("TOKEN_VALIDATION must be introspection, jwt or auto (got %q)", cfg.TokenValidation)
+	}
+	if cfg.MCPSessionIdleTimeout <= 0 {
+		log.Fatal("MCP_SESSION_IDLE_TIMEOUT must be positive")
+	}
+	if cfg.CatalogPollInterval <= 0 {
+		log.Fatal("CATALOG_POLL_INTERVAL must be positive")
+	}
+	if cfg.RegistrationRequireInitialToken && cfg.AdminAPIToken == "" {
+		log.Fatal("ADMIN_API_TOKEN is required when REGISTRATION_REQUIRE_INITIAL_TOKEN is set (tokens are issued through the admin API)")
+	}
+	if cfg.RegistrationRateLimitWindow <= 0 {
+		log.Fatal("REGISTRATION_RATE_LIMIT_WINDOW must be positive")
+	}
+	if cfg.ClientReaperEnabled && (cfg.ClientReaperInterval <= 0 || cfg.ClientReaperMaxIdle <= 0) {
+		log.Fatal("CLIENT_REAPER_INTERVAL and CLIENT_REAPER_MAX_IDLE must be positive")
+	}
+	if cfg.ClientReaperEnabled && cfg.TokenHookSecret == "" {
+		log.Fatal("TOKEN_HOOK_SECRET is required when CLIENT_REAPER_ENABLED is set (the reaper relies on Hydra's token hook)")
+	}
+	if cfg.SessionStore != "postgres" && cfg.SessionStore != "memory" {
+		log.Fatalf("SESSION_STORE must be postgres or memory (got %q)", cfg.SessionStore)
+	}
+	if cfg.SessionLifetime <= 0 || cfg.SessionCleanupInterval <= 0 {
+		log.Fatal("SESSION_LIFETIME and SESSION_CLEANUP_INTERVAL must be positive")
+	}
+	if cfg.LoginMaxFailures < 0 || cfg.LoginIPMaxFailures < 0 {
+		log.Fatal("LOGIN_MAX_FAILURES and LOGIN_IP_MAX_FAILURES must not be negative")
+	}
+	if cfg.LoginFailureWindow <= 0 || cfg.LoginLockoutBase <= 0 || cfg.LoginLockoutMax < cfg.LoginLockoutBase {
+		log.Fatal("LOGIN_FAILURE_WINDOW and LOGIN_LOCKOUT_BASE must be positive and LOGIN_LOCKOUT_MAX at least LOGIN_LOCKOUT_BASE")
+	}
+	if cfg.SecurityLogRetention <= 0 {
+		log.Fatal("SECURITY_LOG_RETENTION must be positive")
+	}
 	// Note: ORY_CLIENT_ID and ORY_CLIENT_SECRET are not required
 	// MCP clients register themselves dynamically via /oauth/register
 
@@ -78,3 +231,27 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsList reads a comma-separated list, ignoring empty entries
+func getEnvAsList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
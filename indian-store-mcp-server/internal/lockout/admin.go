@@ -0,0 +1,90 @@
+package lockout
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Page size limits for /admin/security-log
+const (
+	defaultEventLimit = 100
+	maxEventLimit     = 1000
+)
+
+// AdminHandler serves /admin/lockouts: GET lists locked accounts and IPs
+// (?all=true includes counters that are not locked), DELETE ?email=... or
+// ?ip=... unlocks one
+func AdminHandler(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			lockouts, err := store.Lockouts(r.URL.Query().Get("all") != "true")
+			if err != nil {
+				log.Printf("Failed to list lockouts: %v", err)
+				http.Error(w, "Internal error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"lockouts": lockouts})
+
+		case http.MethodDelete:
+			kind, key := KindAccount, r.URL.Query().Get("email")
+			if key == "" {
+				kind, key = KindIP, r.URL.Query().Get("ip")
+			}
+			if key == "" {
+				http.Error(w, "email or ip is required", http.StatusBadRequest)
+				return
+			}
+
+			found, err := store.Unlock(kind, key)
+			if err != nil {
+				log.Printf("Failed to unlock %s %s: %v", kind, key, err)
+				http.Error(w, "Internal error", http.StatusInternalServerError)
+				return
+			}
+			if !found {
+				http.NotFound(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// EventsHandler serves GET /admin/security-log, newest first, filtered by
+// ?email= and ?ip= and capped by ?limit=
+func EventsHandler(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit := defaultEventLimit
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n <= 0 {
+				http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			limit = min(n, maxEventLimit)
+		}
+
+		events, err := store.Events(r.URL.Query().Get("email"), r.URL.Query().Get("ip"), limit)
+		if err != nil {
+			log.Printf("Failed to read security log: %v", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"events": events})
+	}
+}
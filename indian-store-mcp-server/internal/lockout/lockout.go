@@ -0,0 +1,350 @@
+package lockout
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Kinds of login failure counters
+const (
+	KindAccount = "account" // keyed by the (lower-cased) email that was tried
+	KindIP      = "ip"      // keyed by client IP
+)
+
+// Security log events
+const (
+	EventLoginFailed    = "login_failed"
+	EventLoginBlocked   = "login_blocked" // attempt refused while locked
+	EventLoginSucceeded = "login_succeeded"
+	EventAccountLocked  = "account_locked"
+	EventIPLocked       = "ip_locked"
+	EventUnlocked       = "unlocked"
+)
+
+// Policy configures when and for how long logins are locked
+type Policy struct {
+	MaxFailures   int           // per account; 0 disables account lockout
+	IPMaxFailures int           // per client IP; 0 disables IP lockout
+	Window        time.Duration // quiet time after which the count starts over
+	Base          time.Duration // first lockout
+	Max           time.Duration // longest lockout
+	LogRetention  time.Duration // how long security log entries are kept
+}
+
+// Attempt describes one login attempt
+type Attempt struct {
+	Email     string
+	IP        string
+	UserAgent string
+}
+
+// Lockout is a failure counter, locked or not
+type Lockout struct {
+	Kind          string     `json:"kind"`
+	Key           string     `json:"key"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
+
+// Event is a security log entry
+type Event struct {
+	ID        int64     `json:"id"`
+	Event     string    `json:"event"`
+	Email     string    `json:"email,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store tracks failed logins per account and per client IP in PostgreSQL
+// and locks them out with exponential backoff. Accounts are tracked by the
+// email that was tried whether or not it exists, so a lockout says nothing
+// about which emails are registered. Locks expire on their own; admins can
+// lift them early.
+type Store struct {
+	db     *sql.DB
+	policy Policy
+}
+
+// NewStore creates the store on the shared database connection
+func NewStore(db *sql.DB, policy Policy) (*Store, error) {
+	store := &Store{db: db, policy: policy}
+
+	// Create tables if not exists
+	if err := store.createTables(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// createTables creates the login_failures and security_log tables
+func (s *Store) createTables() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS login_failures (
+		kind VARCHAR(16) NOT NULL,
+		key VARCHAR(255) NOT NULL,
+		failures INTEGER NOT NULL DEFAULT 0,
+		last_failure_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		locked_until TIMESTAMP,
+		PRIMARY KEY (kind, key)
+	);
+	CREATE TABLE IF NOT EXISTS security_log (
+		id BIGSERIAL PRIMARY KEY,
+		event VARCHAR(32) NOT NULL,
+		email VARCHAR(255) NOT NULL DEFAULT '',
+		ip VARCHAR(64) NOT NULL DEFAULT '',
+		user_agent VARCHAR(512) NOT NULL DEFAULT '',
+		detail TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS security_log_created_at_idx ON security_log (created_at);
+	CREATE INDEX IF NOT EXISTS security_log_email_idx ON security_log (email)`
+
+	_, err := s.db.Exec(query)
+	return err
+}
+
+// NormalizeEmail is the account key for an email as typed on the login form
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Check returns how long the attempt has to wait because its account or IP
+// is locked; zero means it may go ahead
+func (s *Store) Check(attempt Attempt) (time.Duration, error) {
+	var seconds int
+	err := s.db.QueryRow(`
+	SELECT COALESCE(MAX(CEIL(EXTRACT(EPOCH FROM locked_until - NOW()))), 0)::integer
+	FROM login_failures
+	WHERE ((kind = $1 AND key = $2) OR (kind = $3 AND key = $4)) AND locked_until > NOW()`,
+		KindAccount, truncate(NormalizeEmail(attempt.Email), 255), KindIP, truncate(attempt.IP, 64)).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+	if seconds > 0 {
+		s.Log(EventLoginBlocked, attempt, "")
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// RecordFailure counts a failed login against its account and IP and locks
+// whichever reached its limit. It returns the resulting lockout, if any.
+func (s *Store) RecordFailure(attempt Attempt) (time.Duration, error) {
+	s.Log(EventLoginFailed, attempt, "")
+
+	var locked time.Duration
+	counters := []struct {
+		kind, key, event string
+		max              int
+	}{
+		{KindAccount, truncate(NormalizeEmail(attempt.Email), 255), EventAccountLocked, s.policy.MaxFailures},
+		{KindIP, truncate(attempt.IP, 64), EventIPLocked, s.policy.IPMaxFailures},
+	}
+	for _, c := range counters {
+		if c.max == 0 || c.key == "" {
+			continue
+		}
+		failures, err := s.countFailure(c.kind, c.key)
+		if err != nil {
+			return locked, err
+		}
+		if failures < c.max {
+			continue
+		}
+
+		d := s.backoff(failures - c.max)
+		_, err = s.db.Exec(`
+		UPDATE login_failures SET locked_until = NOW() + $3::integer * INTERVAL '1 second'
+		WHERE kind = $1 AND key = $2`, c.kind, c.key, int(d.Seconds()))
+		if err != nil {
+			return locked, err
+		}
+		s.Log(c.event, attempt, fmt.Sprintf("locked for %s after %d failures", d, failures))
+		if d > locked {
+			locked = d
+		}
+	}
+	return locked, nil
+}
+
+// countFailure bumps a counter and returns it. A counter that has been quiet
+// for the failure window (counted from the end of its last lockout) starts
+// over, so each lockout of a persistent attacker doubles the last one.
+func (s *Store) countFailure(kind, key string) (int, error) {
+	var failures int
+	err := s.db.QueryRow(`
+	INSERT INTO login_failures (kind, key, failures, last_failure_at)
+	VALUES ($1, $2, 1, NOW())
+	ON CONFLICT (kind, key) DO UPDATE SET
+		failures = CASE
+			WHEN GREATEST(login_failures.last_failure_at, COALESCE(login_failures.locked_until, login_failures.last_failure_at))
+				< NOW() - $3::integer * INTERVAL '1 second'
+			THEN 1
+			ELSE login_failures.failures + 1
+		END,
+		last_failure_at = NOW()
+	RETURNING failures`, kind, key, int(s.policy.Window.Seconds())).Scan(&failures)
+	return failures, err
+}
+
+// backoff is the lockout after the given number of failures past the limit
+func (s *Store) backoff(over int) time.Duration {
+	d := s.policy.Base
+	for i := 0; i < over && d < s.policy.Max; i++ {
+		d *= 2
+	}
+	if d > s.policy.Max {
+		d = s.policy.Max
+	}
+	return d
+}
+
+// RecordSuccess clears the account's failures. The IP counter is left alone
+// so one valid account cannot be used to keep resetting a guessing run.
+func (s *Store) RecordSuccess(attempt Attempt) error {
+	s.Log(EventLoginSucceeded, attempt, "")
+	_, err := s.db.Exec(`DELETE FROM login_failures WHERE kind = $1 AND key = $2`,
+		KindAccount, NormalizeEmail(attempt.Email))
+	return err
+}
+
+// Unlock lifts a lockout and forgets its failures, reporting whether there
+// was anything to clear
+func (s *Store) Unlock(kind, key string) (bool, error) {
+	if kind == KindAccount {
+		key = NormalizeEmail(key)
+	}
+	result, err := s.db.Exec(`DELETE FROM login_failures WHERE kind = $1 AND key = $2`, kind, key)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+
+	attempt := Attempt{}
+	if kind == KindAccount {
+		attempt.Email = key
+	} else {
+		attempt.IP = key
+	}
+	s.Log(EventUnlocked, attempt, "by admin")
+	return true, nil
+}
+
+// Lockouts returns the counters that are currently locked, or all of them
+func (s *Store) Lockouts(lockedOnly bool) ([]Lockout, error) {
+	query := `SELECT kind, key, failures, last_failure_at, locked_until FROM login_failures`
+	if lockedOnly {
+		query += ` WHERE locked_until > NOW()`
+	}
+	query += ` ORDER BY last_failure_at DESC LIMIT 500`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lockouts := []Lockout{}
+	for rows.Next() {
+		var l Lockout
+		var lockedUntil sql.NullTime
+		if err := rows.Scan(&l.Kind, &l.Key, &l.Failures, &l.LastFailureAt, &lockedUntil); err != nil {
+			return nil, err
+		}
+		if lockedUntil.Valid {
+			l.LockedUntil = &lockedUntil.Time
+		}
+		lockouts = append(lockouts, l)
+	}
+	return lockouts, rows.Err()
+}
+
+// Log writes a security log entry and mirrors it to the process log.
+// Failures to store it are logged, never returned.
+func (s *Store) Log(event string, attempt Attempt, detail string) {
+	log.Printf("Security: %s email=%q ip=%s %s", event, attempt.Email, attempt.IP, detail)
+
+	_, err := s.db.Exec(`
+	INSERT INTO security_log (event, email, ip, user_agent, detail) VALUES ($1, $2, $3, $4, $5)`,
+		event, truncate(NormalizeEmail(attempt.Email), 255), truncate(attempt.IP, 64),
+		truncate(attempt.UserAgent, 512), detail)
+	if err != nil {
+		log.Printf("Failed to write security log: %v", err)
+	}
+}
+
+// Events returns the newest security log entries, optionally only those of
+// one email or IP
+func (s *Store) Events(email, ip string, limit int) ([]Event, error) {
+	rows, err := s.db.Query(`
+	SELECT id, event, email, ip, user_agent, detail, created_at
+	FROM security_log
+	WHERE ($1 = '' OR email = $1) AND ($2 = '' OR ip = $2)
+	ORDER BY id DESC
+	LIMIT $3`, NormalizeEmail(email), ip, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Event, &e.Email, &e.IP, &e.UserAgent, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// Cleanup drops expired log entries and counters nobody has touched for a
+// failure window past their lockout
+func (s *Store) Cleanup() (int, error) {
+	result, err := s.db.Exec(`DELETE FROM security_log WHERE created_at < NOW() - $1::integer * INTERVAL '1 second'`,
+		int(s.policy.LogRetention.Seconds()))
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+
+	_, err = s.db.Exec(`
+	DELETE FROM login_failures
+	WHERE GREATEST(last_failure_at, COALESCE(locked_until, last_failure_at)) < NOW() - $1::integer * INTERVAL '1 second'`,
+		int(s.policy.Window.Seconds()))
+	return int(n), err
+}
+
+// RunJanitor calls Cleanup on every tick
+func RunJanitor(store *Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n, err := store.Cleanup()
+		if err != nil {
+			log.Printf("Security log janitor: %v", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("Security log janitor: removed %d old entries", n)
+		}
+	}
+}
+
+// truncate keeps client-supplied values within the column sizes
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+package mfa
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// AdminHandler serves /admin/mfa?email=...: GET shows a user's 2FA status,
+// DELETE resets it (lost device) so they enroll again at their next login
+func AdminHandler(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := r.URL.Query().Get("email")
+		if email == "" {
+			http.Error(w, "email is required", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			status, err := store.Status(email)
+			if err != nil {
+				log.Printf("Failed to load 2FA status of %s: %v", email, err)
+				http.Error(w, "Internal error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(status)
+
+		case http.MethodDelete:
+			found, err := store.Disable(email)
+			if err != nil {
+				log.Printf("Failed to reset 2FA of %s: %v", email, err)
+				http.Error(w, "Internal error", http.StatusInternalServerError)
+				return
+			}
+			if !found {
+				http.NotFound(w, r)
+				return
+			}
+			log.Printf("Two-factor authentication of %s reset by admin", email)
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	recoveryCodeCount    = 10
+	challengeTTL         = 5 * time.Minute
+	maxChallengeAttempts = 5
+)
+
+var (
+	// ErrAlreadyEnabled is returned when enrolling a user who has 2FA on
+	ErrAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrInvalidCode covers wrong, reused and malformed codes
+	ErrInvalidCode = errors.New("invalid verification code")
+	// ErrChallengeNotFound is returned for unknown, expired or exhausted login challenges
+	ErrChallengeNotFound = errors.New("login challenge not found")
+)
+
+// Methods a user can prove the second factor with
+const (
+	MethodTOTP     = "totp"
+	MethodRecovery = "recovery"
+)
+
+// Store keeps TOTP secrets (RFC 6238), recovery codes and the pending
+// second step of logins in PostgreSQL. Recovery codes and challenge IDs are
+// stored as SHA-256 hashes; TOTP secrets have to be stored as they are.
+type Store struct {
+	db     *sql.DB
+	issuer string // shown in authenticator apps
+}
+
+// Enrollment is a TOTP secret waiting to be confirmed with a first code
+type Enrollment struct {
+	Secret string
+	URI    string // otpauth:// provisioning URI
+}
+
+// Status is a user's 2FA state
+type Status struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// Challenge is a login that passed the password check and waits for the
+// second factor
+type Challenge struct {
+	Email          string
+	LoginChallenge string // Hydra login_challenge, empty for account sign-in
+	Verified       bool   // second factor done (set after enrolling during login)
+}
+
+// NewStore creates the store on the shared database connection
+func NewStore(db *sql.DB, issuer string) (*Store, error) {
+	store := &Store{db: db, issuer: issuer}
+
+	// Create tables if not exists
+	if err := store.createTables(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// createTables creates the mfa_totp, mfa_recovery_codes and mfa_challenges tables
+func (s *Store) createTables() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS mfa_totp (
+		email VARCHAR(255) PRIMARY KEY REFERENCES users (email) ON DELETE CASCADE,
+		secret VARCHAR(64) NOT NULL,
+		confirmed_at TIMESTAMP,
+		last_step BIGINT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+		email VARCHAR(255) NOT NULL REFERENCES users (email) ON DELETE CASCADE,
+		code_hash CHAR(64) NOT NULL,
+		used_at TIMESTAMP,
+		PRIMARY KEY (email, code_hash)
+	);
+	CREATE TABLE IF NOT EXISTS mfa_challenges (
+		id_hash CHAR(64) PRIMARY KEY,
+		email VARCHAR(255) NOT NULL,
+		login_challenge TEXT NOT NULL DEFAULT '',
+		verified BOOLEAN NOT NULL DEFAULT FALSE,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		expires_at TIMESTAMP NOT NULL
+	)`
+
+	_, err := s.db.Exec(query)
+	return err
+}
+
+// Enabled reports whether a user has confirmed a TOTP authenticator
+func (s *Store) Enabled(email string) (bool, error) {
+	var enabled bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM mfa_totp WHERE email = $1 AND confirmed_at IS NOT NULL)`,
+		email).Scan(&enabled)
+	return enabled, err
+}
+
+// Status returns a user's 2FA state
+func (s *Store) Status(email string) (*Status, error) {
+	status := &Status{}
+	var confirmedAt sql.NullTime
+	err := s.db.QueryRow(`SELECT confirmed_at FROM mfa_totp WHERE email = $1`, email).Scan(&confirmedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if confirmedAt.Valid {
+		status.Enabled = true
+		status.EnabledAt = &confirmedAt.Time
+	}
+
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM mfa_recovery_codes WHERE email = $1 AND used_at IS NULL`,
+		email).Scan(&status.RecoveryCodesLeft)
+	return status, err
+}
+
+// BeginEnrollment returns the secret the user should add to their
+// authenticator. Until it is confirmed, calling it again returns the same
+// secret so reloading the setup page does not invalidate a scanned code.
+func (s *Store) BeginEnrollment(email string) (*Enrollment, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(`INSERT INTO mfa_totp (email, secret) VALUES ($1, $2) ON CONFLICT (email) DO NOTHING`,
+		email, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	var confirmedAt sql.NullTime
+	err = s.db.QueryRow(`SELECT secret, confirmed_at FROM mfa_totp WHERE email = $1`, email).Scan(&secret, &confirmedAt)
+	if err != nil {
+		return nil, err
+	}
+	if confirmedAt.Valid {
+		return nil, ErrAlreadyEnabled
+	}
+
+	return &Enrollment{Secret: secret, URI: ProvisioningURI(s.issuer, email, secret)}, nil
+}
+
+// ConfirmEnrollment turns 2FA on once the user proves their authenticator
+// works, and returns a fresh set of recovery codes to show them once
+func (s *Store) ConfirmEnrollment(email, code string) ([]string, error) {
+	var secret string
+	err := s.db.QueryRow(`SELECT secret FROM mfa_totp WHERE email = $1 AND confirmed_at IS NULL`, email).Scan(&secret)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := verifyTOTP(secret, normalizeCode(code), time.Now(), 0)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+	result, err := s.db.Exec(`
+	UPDATE mfa_totp SET confirmed_at = NOW(), last_step = $2
+	WHERE email = $1 AND confirmed_at IS NULL`, email, step)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrInvalidCode
+	}
+
+	log.Printf("Two-factor authentication enabled for %s", email)
+	return s.RegenerateRecoveryCodes(email)
+}
+
+// Verify checks a TOTP code or an unused recovery code and returns which
+// one it was. Both are single use.
+func (s *Store) Verify(email, code string) (string, error) {
+	code = normalizeCode(code)
+
+	if isTOTPCode(code) {
+		var secret string
+		var lastStep int64
+		err := s.db.QueryRow(`SELECT secret, last_step FROM mfa_totp WHERE email = $1 AND confirmed_at IS NOT NULL`,
+			email).Scan(&secret, &lastStep)
+		if err == sql.ErrNoRows {
+			return "", ErrInvalidCode
+		}
+		if err != nil {
+			return "", err
+		}
+
+		step, ok := verifyTOTP(secret, code, time.Now(), lastStep)
+		if !ok {
+			return "", ErrInvalidCode
+		}
+		// Only one request can move last_step past this step
+		result, err := s.db.Exec(`UPDATE mfa_totp SET last_step = $2 WHERE email = $1 AND last_step < $2`, email, step)
+		if err != nil {
+			return "", err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return "", ErrInvalidCode
+		}
+		return MethodTOTP, nil
+	}
+
+	result, err := s.db.Exec(`
+	UPDATE mfa_recovery_codes SET used_at = NOW()
+	WHERE email = $1 AND code_hash = $2 AND used_at IS NULL`, email, hashCode(code))
+	if err != nil {
+		return "", err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return "", ErrInvalidCode
+	}
+	log.Printf("Recovery code used by %s", email)
+	return MethodRecovery, nil
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes
+func (s *Store) RegenerateRecoveryCodes(email string) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE email = $1`, email); err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		if _, err := tx.Exec(`INSERT INTO mfa_recovery_codes (email, code_hash) VALUES ($1, $2)`,
+			email, hashCode(normalizeCode(code))); err != nil {
+			return nil, err
+		}
+	}
+	return codes, tx.Commit()
+}
+
+// Disable turns 2FA off and forgets the secret and recovery codes,
+// reporting whether the user had anything set up
+func (s *Store) Disable(email string) (bool, error) {
+	result, err := s.db.Exec(`DELETE FROM mfa_totp WHERE email = $1`, email)
+	if err != nil {
+		return false, err
+	}
+	if _, err := s.db.Exec(`DELETE FROM mfa_recovery_codes WHERE email = $1`, email); err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if n > 0 {
+		log.Printf("Two-factor authentication disabled for %s", email)
+	}
+	return n > 0, err
+}
+
+// StartChallenge records a login waiting for its second factor and returns
+// the ID to put in the browser's cookie
+func (s *Store) StartChallenge(email, loginChallenge string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+
+	_, err := s.db.Exec(`
+	INSERT INTO mfa_challenges (id_hash, email, login_challenge, expires_at)
+	VALUES ($1, $2, $3, NOW() + $4::integer * INTERVAL '1 second')`,
+		hashCode(id), email, loginChallenge, int(challengeTTL.Seconds()))
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Challenge returns a pending login by cookie value
+func (s *Store) Challenge(id string) (*Challenge, error) {
+	var c Challenge
+	err := s.db.QueryRow(`
+	SELECT email, login_challenge, verified FROM mfa_challenges
+	WHERE id_hash = $1 AND expires_at > NOW() AND attempts < $2`, hashCode(id), maxChallengeAttempts).
+		Scan(&c.Email, &c.LoginChallenge, &c.Verified)
+	if err == sql.ErrNoRows {
+		return nil, ErrChallengeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// FailChallenge counts a wrong code; after maxChallengeAttempts the login
+// has to start over with the password
+func (s *Store) FailChallenge(id string) error {
+	_, err := s.db.Exec(`UPDATE mfa_challenges SET attempts = attempts + 1 WHERE id_hash = $1`, hashCode(id))
+	return err
+}
+
+// VerifyChallenge marks the second factor of a pending login as done
+func (s *Store) VerifyChallenge(id string) error {
+	_, err := s.db.Exec(`UPDATE mfa_challenges SET verified = TRUE WHERE id_hash = $1`, hashCode(id))
+	return err
+}
+
+// EndChallenge removes a pending login
+func (s *Store) EndChallenge(id string) error {
+	_, err := s.db.Exec(`DELETE FROM mfa_challenges WHERE id_hash = $1`, hashCode(id))
+	return err
+}
+
+// DeleteExpiredChallenges removes logins that were never finished
+func (s *Store) DeleteExpiredChallenges() (int, error) {
+	result, err := s.db.Exec(`DELETE FROM mfa_challenges WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// RunJanitor removes expired login challenges on every tick
+func RunJanitor(store *Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := store.DeleteExpiredChallenges(); err != nil {
+			log.Printf("MFA janitor: %v", err)
+		}
+	}
+}
+
+// newRecoveryCode returns a code like "k7d2m-q9xfa" (50 random bits)
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := normalizeCode(base32.StdEncoding.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
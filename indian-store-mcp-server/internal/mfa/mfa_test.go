@@ -0,0 +1,137 @@
+package mfa
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"indian-store-mcp-server/internal/database"
+	"indian-store-mcp-server/internal/users"
+)
+
+func TestRecoveryCodeFormat(t *testing.T) {
+	shape := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	for i := 0; i < 20; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !shape.MatchString(code) {
+			t.Fatalf("newRecoveryCode() = %q", code)
+		}
+		if isTOTPCode(normalizeCode(code)) {
+			t.Fatalf("recovery code %q is taken for a TOTP code", code)
+		}
+
+		// However it is typed, it hashes to what RegenerateRecoveryCodes stores
+		stored := hashCode(normalizeCode(code))
+		for _, typed := range []string{strings.ToUpper(code), strings.Replace(code, "-", " ", 1), strings.Replace(code, "-", "", 1)} {
+			if hashCode(normalizeCode(typed)) != stored {
+				t.Errorf("%q does not match recovery code %q", typed, code)
+			}
+		}
+	}
+}
+
+// testStore connects to the scratch database in TEST_DATABASE_URL and
+// creates a user who has just enabled 2FA. It skips the test when no
+// database is set.
+func testStore(t *testing.T) (store *Store, email string, recoveryCodes []string) {
+	t.Helper()
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := database.Open(databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	userStore, err := users.NewUserStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err = NewStore(db, "Test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	email = fmt.Sprintf("mfa-test-%d@example.com", time.Now().UnixNano())
+	if err := userStore.AddUser(email, "correct horse battery staple", "MFA Test"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { userStore.DeleteUser(email) })
+
+	enrollment, err := store.BeginEnrollment(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoveryCodes, err = store.ConfirmEnrollment(email, currentCode(t, enrollment.Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, email, recoveryCodes
+}
+
+// currentCode is the code the authenticator shows now
+func currentCode(t *testing.T, secret string) string {
+	t.Helper()
+	key, err := secretEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hotp(key, uint64(time.Now().Unix()/totpPeriod))
+}
+
+func TestRecoveryCodeSingleUse(t *testing.T) {
+	store, email, codes := testStore(t)
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	method, err := store.Verify(email, strings.ToUpper(codes[0]))
+	if err != nil || method != MethodRecovery {
+		t.Fatalf("Verify(recovery code) = %q, %v", method, err)
+	}
+	if _, err := store.Verify(email, codes[0]); err != ErrInvalidCode {
+		t.Errorf("second Verify(recovery code) = %v, want ErrInvalidCode", err)
+	}
+	if _, err := store.Verify(email, codes[1]); err != nil {
+		t.Errorf("Verify(another recovery code) = %v", err)
+	}
+
+	status, err := store.Status(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.RecoveryCodesLeft != recoveryCodeCount-2 {
+		t.Errorf("RecoveryCodesLeft = %d, want %d", status.RecoveryCodesLeft, recoveryCodeCount-2)
+	}
+}
+
+func TestTOTPReplayRejected(t *testing.T) {
+	store, email, _ := testStore(t)
+	var secret string
+	var lastStep int64
+	err := store.db.QueryRow(`SELECT secret, last_step FROM mfa_totp WHERE email = $1`, email).Scan(&secret, &lastStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := secretEncoding.DecodeString(secret)
+
+	// The enrollment code used up its step, so only a later one works
+	if _, err := store.Verify(email, hotp(key, uint64(lastStep))); err != ErrInvalidCode {
+		t.Errorf("Verify(enrollment code) = %v, want ErrInvalidCode", err)
+	}
+	next := hotp(key, uint64(lastStep+1))
+	if method, err := store.Verify(email, next); err != nil || method != MethodTOTP {
+		t.Fatalf("Verify(next code) = %q, %v", method, err)
+	}
+	if _, err := store.Verify(email, next); err != ErrInvalidCode {
+		t.Errorf("Verify(replayed code) = %v, want ErrInvalidCode", err)
+	}
+}
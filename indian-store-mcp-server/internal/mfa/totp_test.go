@@ -0,0 +1,135 @@
+package mfa
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of the RFC 4226 and RFC 6238 test vectors
+var rfcSecret = secretEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226 appendix D
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range want {
+		if got := hotp([]byte("12345678901234567890"), uint64(counter)); got != code {
+			t.Errorf("hotp(%d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestVerifyTOTPVectors(t *testing.T) {
+	// RFC 6238 appendix B (SHA-1), last six of the eight digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			step, ok := verifyTOTP(rfcSecret, tt.code, time.Unix(tt.unix, 0), 0)
+			if !ok {
+				t.Fatal("verifyTOTP() rejected the RFC code")
+			}
+			if step != tt.unix/totpPeriod {
+				t.Errorf("step = %d, want %d", step, tt.unix/totpPeriod)
+			}
+		})
+	}
+}
+
+func TestVerifyTOTPWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+	key, _ := secretEncoding.DecodeString(rfcSecret)
+
+	tests := []struct {
+		name   string
+		offset int64
+		valid  bool
+	}{
+		{"two steps behind", -2, false},
+		{"one step behind", -1, true},
+		{"current step", 0, true},
+		{"one step ahead", 1, true},
+		{"two steps ahead", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := hotp(key, uint64(current+tt.offset))
+			step, ok := verifyTOTP(rfcSecret, code, now, 0)
+			if ok != tt.valid {
+				t.Fatalf("verifyTOTP() = %v, want %v", ok, tt.valid)
+			}
+			if ok && step != current+tt.offset {
+				t.Errorf("step = %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestVerifyTOTPReplay(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+	key, _ := secretEncoding.DecodeString(rfcSecret)
+	code := hotp(key, uint64(current))
+
+	step, ok := verifyTOTP(rfcSecret, code, now, 0)
+	if !ok {
+		t.Fatal("verifyTOTP() rejected a fresh code")
+	}
+	if _, ok := verifyTOTP(rfcSecret, code, now, step); ok {
+		t.Error("verifyTOTP() accepted the same code twice")
+	}
+	if _, ok := verifyTOTP(rfcSecret, code, now.Add(totpPeriod*time.Second), step); ok {
+		t.Error("verifyTOTP() accepted the code again in the next step")
+	}
+
+	// An older code still inside the window is refused once a newer one was used
+	previous := hotp(key, uint64(current-1))
+	if _, ok := verifyTOTP(rfcSecret, previous, now, step); ok {
+		t.Error("verifyTOTP() accepted a code older than the last one used")
+	}
+	if _, ok := verifyTOTP(rfcSecret, hotp(key, uint64(current+1)), now, step); !ok {
+		t.Error("verifyTOTP() rejected the next step's code")
+	}
+}
+
+func TestVerifyTOTPMalformed(t *testing.T) {
+	now := time.Unix(59, 0)
+	tests := map[string]struct{ secret, code string }{
+		"short code":   {rfcSecret, "28708"},
+		"long code":    {rfcSecret, "2870820"},
+		"eight digits": {rfcSecret, "94287082"},
+		"bad secret":   {"not base32!", "287082"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, ok := verifyTOTP(tt.secret, tt.code, now, 0); ok {
+				t.Error("verifyTOTP() accepted the code")
+			}
+		})
+	}
+
+	if _, ok := verifyTOTP(strings.ToLower(rfcSecret), "287082", now, 0); !ok {
+		t.Error("verifyTOTP() rejected a lowercase secret")
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	secret, err := newSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := secretEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Errorf("newSecret() = %q, want 160 bits of base32", secret)
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+
+	"indian-store-mcp-server/internal/config"
+	"indian-store-mcp-server/internal/oauth"
+)
+
+// AdminOnly guards operator endpoints with the ADMIN_API_TOKEN bearer token.
+// When no token is configured the endpoints do not exist.
+func AdminOnly(cfg *config.Config, next http.HandlerFunc) http.HandlerFunc {
+	adminToken := cfg.AdminAPIToken
+	proxies := oauth.NewTrustedProxies(cfg)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		authHeader := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(authHeader, "Bearer ")
+		if !strings.HasPrefix(authHeader, "Bearer ") || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			log.Printf("Rejected admin request to %s from %s", r.URL.Path, proxies.ClientIP(r))
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
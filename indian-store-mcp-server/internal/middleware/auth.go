@@ -1,20 +1,56 @@
 package middleware
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"indian-store-mcp-server/internal/oauth"
 )
 
+type contextKey string
+
+const principalKey contextKey = "principal"
+
+// Principal is the caller identified by an access token
+type Principal struct {
+	Subject   string
+	Email     string
+	ClientID  string
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+// HasScope reports whether the token was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingScopes returns the entries of required the token was not granted
+func (p *Principal) MissingScopes(required []string) []string {
+	var missing []string
+	for _, scope := range required {
+		if !p.HasScope(scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
 type AuthMiddleware struct {
-	oryClient *oauth.OryClient
+	validator *oauth.TokenValidator
 }
 
-func NewAuthMiddleware(oryClient *oauth.OryClient) *AuthMiddleware {
+func NewAuthMiddleware(validator *oauth.TokenValidator) *AuthMiddleware {
 	return &AuthMiddleware{
-		oryClient: oryClient,
+		validator: validator,
 	}
 }
 
@@ -24,7 +60,7 @@ func (m *AuthMiddleware) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
 			log.Println("Missing Authorization header")
-			http.Error(w, "Unauthorized: Missing token", http.StatusUnauthorized)
+			oauth.WriteBearerError(w, r, http.StatusUnauthorized, "", "Missing access token")
 			return
 		}
 
@@ -34,37 +70,64 @@ func (m *AuthMiddleware) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 			token = authHeader[7:]
 		} else {
 			log.Println("Invalid Authorization header format")
-			http.Error(w, "Unauthorized: Invalid token format", http.StatusUnauthorized)
+			oauth.WriteBearerError(w, r, http.StatusBadRequest, oauth.ErrInvalidRequest, "Authorization header must use the Bearer scheme")
 			return
 		}
 
-		// Validate token with Ory
-		introResp, err := m.oryClient.IntrospectToken(token)
+		// Validate token locally (JWT) or with Ory (introspection)
+		introResp, err := m.validator.Validate(token)
 		if err != nil {
-			log.Printf("Token introspection failed: %v", err)
-			http.Error(w, "Unauthorized: Invalid token", http.StatusUnauthorized)
+			log.Printf("Token validation failed: %v", err)
+			oauth.WriteBearerError(w, r, http.StatusUnauthorized, oauth.ErrInvalidToken, "The access token is invalid")
 			return
 		}
 
 		if !introResp.Active {
 			log.Println("Token is not active")
-			http.Error(w, "Unauthorized: Token expired or invalid", http.StatusUnauthorized)
+			oauth.WriteBearerError(w, r, http.StatusUnauthorized, oauth.ErrInvalidToken, "The access token expired or was revoked")
 			return
 		}
 
-		log.Printf("Authenticated user: %s (%s)", introResp.Email, introResp.Sub)
-		
-		// Token is valid, proceed to handler
-		next(w, r)
+		log.Printf("Authenticated user: %s (%s) via client %s, scope: %q",
+			introResp.Email, introResp.Sub, introResp.ClientID, introResp.Scope)
+
+		principal := &Principal{
+			Subject:  introResp.Sub,
+			Email:    introResp.Email,
+			ClientID: introResp.ClientID,
+			Scopes:   oauth.ParseScope(introResp.Scope),
+		}
+		if introResp.Exp > 0 {
+			principal.ExpiresAt = time.Unix(introResp.Exp, 0)
+		}
+
+		// Token is valid, proceed to handler with the caller's identity
+		ctx := context.WithValue(r.Context(), principalKey, principal)
+		next(w, r.WithContext(ctx))
 	}
 }
 
+// PrincipalFromContext returns the caller authenticated by RequireAuth, or
+// an empty principal with no scopes
+func PrincipalFromContext(ctx context.Context) *Principal {
+	if principal, ok := ctx.Value(principalKey).(*Principal); ok {
+		return principal
+	}
+	return &Principal{}
+}
+
+// SubjectFromContext returns the subject authenticated by RequireAuth
+func SubjectFromContext(ctx context.Context) string {
+	return PrincipalFromContext(ctx).Subject
+}
+
 // CORS middleware for handling cross-origin requests
 func CORS(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Mcp-Session-Id, Mcp-Protocol-Version, Last-Event-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "Mcp-Session-Id, WWW-Authenticate")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 
 		if r.Method == "OPTIONS" {
@@ -0,0 +1,440 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"indian-store-mcp-server/internal/config"
+	"indian-store-mcp-server/internal/lockout"
+	"indian-store-mcp-server/internal/mfa"
+	"indian-store-mcp-server/internal/sessions"
+	"indian-store-mcp-server/internal/users"
+)
+
+// Account pages, signed in with the same session_id cookie as /login
+const (
+	accountAppsPath   = "/account/apps"
+	accountAPIAppPath = "/account/api/apps"
+	accountMFAPath    = "/account/mfa"
+)
+
+// AccountHandler lets a signed-in user see and revoke the clients they
+// granted access to and manage their two-factor authentication. Grants are
+// Hydra consent sessions; revoking one also revokes the tokens Hydra issued
+// under it.
+type AccountHandler struct {
+	oryClient        *OryClient
+	validator        *TokenValidator
+	sessions         sessions.SessionStore
+	userStore        *users.UserStore
+	mfa              *mfa.Store
+	lockouts         *lockout.Store
+	mfaRequiredRoles []string
+	proxies          *TrustedProxies
+}
+
+// AuthorizedApp is a client the user has granted access to
+type AuthorizedApp struct {
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	LogoURI    string    `json:"logo_uri,omitempty"`
+	Scopes     []string  `json:"scopes"`
+	GrantedAt  time.Time `json:"granted_at"`
+}
+
+func NewAccountHandler(cfg *config.Config, oryClient *OryClient, validator *TokenValidator, sessionStore sessions.SessionStore, userStore *users.UserStore, mfaStore *mfa.Store, lockouts *lockout.Store) *AccountHandler {
+	return &AccountHandler{
+		oryClient:        oryClient,
+		validator:        validator,
+		sessions:         sessionStore,
+		userStore:        userStore,
+		mfa:              mfaStore,
+		lockouts:         lockouts,
+		mfaRequiredRoles: cfg.MFARequiredRoles,
+		proxies:          NewTrustedProxies(cfg),
+	}
+}
+
+// authorizedApps merges a user's consent sessions into one entry per client
+// with the union of granted scopes and the latest grant time
+func (h *AccountHandler) authorizedApps(subject string) ([]AuthorizedApp, error) {
+	consents, err := h.oryClient.ListConsentSessions(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	byClient := make(map[string]*AuthorizedApp)
+	apps := []AuthorizedApp{}
+	for _, consent := range consents {
+		client := consent.ConsentRequest.Client
+		app, ok := byClient[client.ClientID]
+		if !ok {
+			name := client.ClientName
+			if name == "" {
+				name = client.ClientID
+			}
+			apps = append(apps, AuthorizedApp{
+				ClientID:   client.ClientID,
+				ClientName: name,
+				LogoURI:    httpsURL(client.LogoURI),
+				Scopes:     []string{},
+			})
+			app = &apps[len(apps)-1]
+			byClient[client.ClientID] = app
+		}
+		for _, scope := range consent.GrantScope {
+			if !contains(app.Scopes, scope) {
+				app.Scopes = append(app.Scopes, scope)
+			}
+		}
+		if consent.HandledAt.After(app.GrantedAt) {
+			app.GrantedAt = consent.HandledAt
+		}
+	}
+
+	sort.Slice(apps, func(i, j int) bool { return apps[i].GrantedAt.After(apps[j].GrantedAt) })
+	return apps, nil
+}
+
+// revoke withdraws the user's consent to clientID, or to every client when
+// clientID is empty
+func (h *AccountHandler) revoke(subject, clientID string) error {
+	if err := h.oryClient.RevokeConsentSessions(subject, clientID); err != nil {
+		return err
+	}
+	h.validator.ForgetGrant(subject, clientID)
+	if clientID == "" {
+		log.Printf("User %s revoked access for all apps", subject)
+	} else {
+		log.Printf("User %s revoked access for client %s", subject, clientID)
+	}
+	return nil
+}
+
+// HandleApps serves the /account/apps page. POST revokes the client named
+// by client_id, or all clients when all=true.
+func (h *AccountHandler) HandleApps(w http.ResponseWriter, r *http.Request) {
+	session, ok := sessionFromRequest(h.sessions, r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	notice := ""
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		r.ParseForm()
+		if subtle.ConstantTimeCompare([]byte(r.FormValue("csrf")), []byte(accountCSRFToken(r))) != 1 {
+			http.Error(w, "Invalid form submission", http.StatusForbidden)
+			return
+		}
+
+		clientID := r.FormValue("client_id")
+		if clientID == "" && r.FormValue("all") != "true" {
+			http.Error(w, "Missing client_id", http.StatusBadRequest)
+			return
+		}
+		if err := h.revoke(session.Email, clientID); err != nil {
+			log.Printf("Error revoking consent for %s: %v", session.Email, err)
+			http.Error(w, "Error revoking access", http.StatusBadGateway)
+			return
+		}
+		notice = "Access revoked."
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	apps, err := h.authorizedApps(session.Email)
+	if err != nil {
+		log.Printf("Error listing consent sessions for %s: %v", session.Email, err)
+		http.Error(w, "Error loading your apps", http.StatusBadGateway)
+		return
+	}
+
+	h.showAppsPage(w, r, session, apps, notice)
+}
+
+// HandleAppsAPI is the JSON form of the account page:
+//
+//	GET    /account/api/apps              list authorized apps
+//	DELETE /account/api/apps              revoke every app
+//	DELETE /account/api/apps/{client_id}  revoke one app
+func (h *AccountHandler) HandleAppsAPI(w http.ResponseWriter, r *http.Request) {
+	session, ok := sessionFromRequest(h.sessions, r)
+	if !ok {
+		jsonError(w, "login_required", "Sign in at /login first", http.StatusUnauthorized)
+		return
+	}
+
+	clientID := ""
+	if rest := strings.TrimPrefix(r.URL.Path, accountAPIAppPath); rest != "" {
+		if !strings.HasPrefix(rest, "/") || rest == "/" {
+			http.NotFound(w, r)
+			return
+		}
+		clientID = rest[1:]
+	}
+
+	switch {
+	case r.Method == http.MethodGet && clientID == "":
+		apps, err := h.authorizedApps(session.Email)
+		if err != nil {
+			log.Printf("Error listing consent sessions for %s: %v", session.Email, err)
+			jsonError(w, "server_error", "Failed to list apps", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(map[string]interface{}{"apps": apps})
+
+	case r.Method == http.MethodDelete:
+		if err := h.revoke(session.Email, clientID); err != nil {
+			log.Printf("Error revoking consent for %s: %v", session.Email, err)
+			jsonError(w, "server_error", "Failed to revoke access", http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		if clientID == "" {
+			w.Header().Set("Allow", "GET, DELETE")
+		} else {
+			w.Header().Set("Allow", "DELETE")
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// showAppsPage renders the list of authorized apps
+func (h *AccountHandler) showAppsPage(w http.ResponseWriter, r *http.Request, session *sessions.Session, apps []AuthorizedApp, notice string) {
+	tmpl := `<!DOCTYPE html>
+<html>
+<head>
+    <title>Indian Store MCP - Connected apps</title>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 640px; margin: 60px auto; padding: 20px; color: #333; }
+        h1 { font-size: 24px; margin: 0 0 5px 0; }
+        .subtitle { color: #666; font-size: 14px; margin: 0 0 25px 0; }
+        .notice { background: #e8f5e9; color: #2e7d32; padding: 10px; border-radius: 5px; margin-bottom: 20px; font-size: 14px; }
+        .app { display: flex; align-items: flex-start; border: 1px solid #e0e0e0; border-radius: 10px; padding: 15px; margin-bottom: 12px; }
+        .app img { width: 40px; height: 40px; border-radius: 8px; margin-right: 15px; object-fit: contain; }
+        .app .info { flex: 1; }
+        .app h2 { font-size: 16px; margin: 0 0 4px 0; }
+        .meta { color: #888; font-size: 12px; }
+        .scopes { margin: 8px 0 0 0; padding-left: 18px; font-size: 13px; }
+        button { padding: 8px 14px; border-radius: 5px; border: none; font-size: 14px; cursor: pointer; background: #eee; color: #c62828; }
+        .all { margin-top: 15px; }
+        .footer { margin-top: 30px; font-size: 13px; }
+        .footer a { color: #667eea; }
+    </style>
+</head>
+<body>
+    <h1>Connected apps</h1>
+    <p class="subtitle">Signed in as <strong>{{.Email}}</strong>. Revoking an app cuts it off within minutes; it has to ask your permission again to reconnect.</p>
+    {{if .Notice}}<div class="notice">{{.Notice}}</div>{{end}}
+
+    {{range .Apps}}
+    <div class="app">
+        {{if .LogoURI}}<img src="{{.LogoURI}}" alt="">{{end}}
+        <div class="info">
+            <h2>{{.ClientName}}</h2>
+            <div class="meta">Granted {{.GrantedAt.Format "2 Jan 2006 15:04 MST"}}</div>
+            <ul class="scopes">
+            {{range .Scopes}}<li>{{scopeDescription .}}</li>{{end}}
+            </ul>
+        </div>
+        <form method="POST">
+            <input type="hidden" name="csrf" value="{{$.CSRF}}">
+            <input type="hidden" name="client_id" value="{{.ClientID}}">
+            <button type="submit">Revoke</button>
+        </form>
+    </div>
+    {{else}}
+    <p>You have not connected any apps.</p>
+    {{end}}
+
+    {{if gt (len .Apps) 1}}
+    <form method="POST" class="all">
+        <input type="hidden" name="csrf" value="{{.CSRF}}">
+        <input type="hidden" name="all" value="true">
+        <button type="submit">Revoke all</button>
+    </form>
+    {{end}}
+
+    <div class="footer"><a href="/account/mfa">Two-factor authentication</a> &middot; <a href="/logout">Sign out</a></div>
+</body>
+</html>`
+
+	t := template.Must(template.New("apps").Funcs(template.FuncMap{
+		"scopeDescription": ScopeDescription,
+	}).Parse(tmpl))
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	t.Execute(w, map[string]interface{}{
+		"Email":  session.Email,
+		"Apps":   apps,
+		"CSRF":   accountCSRFToken(r),
+		"Notice": notice,
+	})
+}
+
+// HandleMFA serves /account/mfa, where a signed-in user turns on TOTP 2FA,
+// gets new recovery codes or turns it off again (unless their role
+// requires it). Changes that need a code count wrong codes against the
+// login lockout.
+func (h *AccountHandler) HandleMFA(w http.ResponseWriter, r *http.Request) {
+	session, ok := sessionFromRequest(h.sessions, r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+	user, ok := h.userStore.GetUser(session.Email)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	page := mfaPage{
+		Email:      user.Email,
+		FormAction: accountMFAPath,
+		CSRF:       accountCSRFToken(r),
+		Required:   contains(h.mfaRequiredRoles, user.Role),
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		r.ParseForm()
+		if subtle.ConstantTimeCompare([]byte(r.FormValue("csrf")), []byte(page.CSRF)) != 1 {
+			http.Error(w, "Invalid form submission", http.StatusForbidden)
+			return
+		}
+
+		attempt := lockout.Attempt{Email: user.Email, IP: h.proxies.LockoutIP(r), UserAgent: r.UserAgent()}
+		wait, err := h.lockouts.Check(attempt)
+		if err != nil {
+			log.Printf("Error checking login lockout: %v", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		if wait > 0 {
+			page.Error = lockedMessage(wait)
+			break
+		}
+
+		code := r.FormValue("code")
+		switch r.FormValue("action") {
+		case "enroll":
+			codes, err := h.mfa.ConfirmEnrollment(user.Email, code)
+			if err == nil {
+				page.State = "codes"
+				page.Codes = codes
+				renderMFAPage(w, page)
+				return
+			}
+			page.Error = h.codeError(attempt, err)
+
+		case "regenerate", "disable":
+			if r.FormValue("action") == "disable" && page.Required {
+				http.Error(w, "Two-factor authentication is required for your account", http.StatusForbidden)
+				return
+			}
+			if _, err := h.mfa.Verify(user.Email, code); err != nil {
+				page.Error = h.codeError(attempt, err)
+				break
+			}
+
+			if r.FormValue("action") == "disable" {
+				if _, err := h.mfa.Disable(user.Email); err != nil {
+					log.Printf("Error disabling 2FA of %s: %v", user.Email, err)
+					http.Error(w, "Internal error", http.StatusInternalServerError)
+					return
+				}
+				page.Notice = "Two-factor authentication is off."
+				break
+			}
+			codes, err := h.mfa.RegenerateRecoveryCodes(user.Email)
+			if err != nil {
+				log.Printf("Error regenerating recovery codes of %s: %v", user.Email, err)
+				http.Error(w, "Internal error", http.StatusInternalServerError)
+				return
+			}
+			page.State = "codes"
+			page.Codes = codes
+			renderMFAPage(w, page)
+			return
+
+		default:
+			http.Error(w, "Unknown action", http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status, err := h.mfa.Status(user.Email)
+	if err != nil {
+		log.Printf("Error loading 2FA status of %s: %v", user.Email, err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	if status.Enabled {
+		page.State = "status"
+		page.Status = status
+		renderMFAPage(w, page)
+		return
+	}
+
+	enrollment, err := h.mfa.BeginEnrollment(user.Email)
+	if err != nil {
+		log.Printf("Error starting 2FA enrollment for %s: %v", user.Email, err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	page.State = "setup"
+	page.Secret = groupSecret(enrollment.Secret)
+	page.URI = template.URL(enrollment.URI)
+	renderMFAPage(w, page)
+}
+
+// codeError turns a failed code check into the message to show, counting
+// wrong codes as failed logins
+func (h *AccountHandler) codeError(attempt lockout.Attempt, err error) string {
+	if err != mfa.ErrInvalidCode {
+		log.Printf("Error checking 2FA code of %s: %v", attempt.Email, err)
+		return "Something went wrong, please try again"
+	}
+	wait, err := h.lockouts.RecordFailure(attempt)
+	if err != nil {
+		log.Printf("Error recording failed login: %v", err)
+	}
+	if wait > 0 {
+		return lockedMessage(wait)
+	}
+	return "Invalid code"
+}
+
+// accountCSRFToken ties the page's forms to the session cookie, which a
+// cross-site page cannot read
+func accountCSRFToken(r *http.Request) string {
+	cookie, err := r.Cookie("session_id")
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte("account-csrf:" + cookie.Value))
+	return hex.EncodeToString(sum[:])
+}
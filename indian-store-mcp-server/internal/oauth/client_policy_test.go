@@ -0,0 +1,54 @@
+package oauth
+
+import "testing"
+
+func TestValidateClientMetadataLogoURI(t *testing.T) {
+	tests := []struct {
+		logoURI string
+		valid   bool
+	}{
+		{"", true},
+		{"https://example.com/logo.png", true},
+		{"http://example.com/logo.png", false},
+		{"javascript:alert(1)", false},
+		{"/logo.png", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.logoURI, func(t *testing.T) {
+			req := &ClientRegistrationRequest{
+				LogoURI:                 tt.logoURI,
+				GrantTypes:              []string{"authorization_code"},
+				ResponseTypes:           []string{"code"},
+				TokenEndpointAuthMethod: AuthMethodSecretBasic,
+			}
+			if err := validateClientMetadata(req); (err == nil) != tt.valid {
+				t.Errorf("validateClientMetadata() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestHasPKCE(t *testing.T) {
+	const authorize = "https://auth.example.com/oauth2/auth?client_id=c&response_type=code"
+
+	tests := []struct {
+		name       string
+		authMethod string
+		requestURL string
+		want       bool
+	}{
+		{"public with S256", AuthMethodNone, authorize + "&code_challenge=abc&code_challenge_method=S256", true},
+		{"public without challenge", AuthMethodNone, authorize, false},
+		{"public with plain", AuthMethodNone, authorize + "&code_challenge=abc&code_challenge_method=plain", false},
+		{"public with method only", AuthMethodNone, authorize + "&code_challenge_method=S256", false},
+		{"public with bad URL", AuthMethodNone, "%zz", false},
+		{"confidential without challenge", AuthMethodSecretBasic, authorize, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasPKCE(tt.authMethod, tt.requestURL); got != tt.want {
+				t.Errorf("hasPKCE() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
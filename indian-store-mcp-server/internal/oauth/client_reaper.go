@@ -0,0 +1,182 @@
+package oauth
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"indian-store-mcp-server/internal/config"
+)
+
+// maxReapPerRun bounds the Hydra deletes made by a single run
+const maxReapPerRun = 500
+
+// ClientReaper deletes dynamically registered clients that have not been
+// issued a token within the idle window. MCP clients often register again
+// on every reconnect, leaving the old client behind in Hydra. Only clients
+// registered through /oauth/register are ever considered.
+type ClientReaper struct {
+	oryClient *OryClient
+	store     *RegistrationStore
+	maxIdle   time.Duration
+	dryRun    bool
+	hookToken string
+	proxies   *TrustedProxies
+}
+
+// ReapReport describes one reaper run
+type ReapReport struct {
+	DryRun         bool                 `json:"dry_run"`
+	MaxIdleSeconds int                  `json:"max_idle_seconds"`
+	Clients        []ClientRegistration `json:"clients"` // deleted, or would be in a dry run
+	Deleted        int                  `json:"deleted"`
+	Failed         int                  `json:"failed"`
+}
+
+// tokenHookRequest is the part of Hydra's token hook payload we use
+type tokenHookRequest struct {
+	Request struct {
+		ClientID string `json:"client_id"`
+	} `json:"request"`
+}
+
+func NewClientReaper(cfg *config.Config, oryClient *OryClient, store *RegistrationStore) *ClientReaper {
+	return &ClientReaper{
+		oryClient: oryClient,
+		store:     store,
+		maxIdle:   time.Duration(cfg.ClientReaperMaxIdle) * time.Second,
+		dryRun:    cfg.ClientReaperDryRun,
+		hookToken: cfg.TokenHookSecret,
+		proxies:   NewTrustedProxies(cfg),
+	}
+}
+
+// Run reaps idle clients once. In a dry run nothing is deleted and the
+// report lists what would have been.
+func (c *ClientReaper) Run(dryRun bool) (*ReapReport, error) {
+	idle, err := c.store.Idle(c.maxIdle, maxReapPerRun)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReapReport{
+		DryRun:         dryRun,
+		MaxIdleSeconds: int(c.maxIdle.Seconds()),
+		Clients:        []ClientRegistration{},
+	}
+	if dryRun {
+		report.Clients = idle
+		return report, nil
+	}
+
+	for _, reg := range idle {
+		if err := c.oryClient.DeleteClient(reg.ClientID); err != nil && err != ErrClientNotFound {
+			log.Printf("Client reaper: failed to delete client %s: %v", reg.ClientID, err)
+			report.Failed++
+			continue
+		}
+		if err := c.store.Delete(reg.ClientID); err != nil {
+			log.Printf("Client reaper: failed to delete registration of %s: %v", reg.ClientID, err)
+		}
+		report.Clients = append(report.Clients, reg)
+		report.Deleted++
+	}
+	return report, nil
+}
+
+// RunPeriodically reaps on every tick. Replicas may run concurrently;
+// deleting an already deleted client is harmless.
+func (c *ClientReaper) RunPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		report, err := c.Run(c.dryRun)
+		if err != nil {
+			log.Printf("Client reaper: %v", err)
+			continue
+		}
+		if report.DryRun {
+			for _, reg := range report.Clients {
+				log.Printf("Client reaper (dry run): would delete %s (%s, created %s)",
+					reg.ClientID, reg.ClientName, reg.CreatedAt.Format(time.RFC3339))
+			}
+			log.Printf("Client reaper (dry run): %d idle clients", len(report.Clients))
+		} else if report.Deleted > 0 || report.Failed > 0 {
+			log.Printf("Client reaper: deleted %d idle clients, %d failed", report.Deleted, report.Failed)
+		}
+	}
+}
+
+// HandleReaper is the admin endpoint on /admin/clients/reap. GET reports
+// what a run would delete; POST runs one, as a dry run unless
+// ?dry_run=false is given.
+func (c *ClientReaper) HandleReaper(w http.ResponseWriter, r *http.Request) {
+	dryRun := true
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if value := r.URL.Query().Get("dry_run"); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				jsonError(w, "invalid_request", "dry_run must be true or false", http.StatusBadRequest)
+				return
+			}
+			dryRun = parsed
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, err := c.Run(dryRun)
+	if err != nil {
+		log.Printf("Client reaper: %v", err)
+		jsonError(w, "server_error", "Failed to reap clients", http.StatusInternalServerError)
+		return
+	}
+	if !dryRun {
+		log.Printf("Client reaper (admin): deleted %d idle clients, %d failed", report.Deleted, report.Failed)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleTokenHook receives Hydra's token hook (oauth2.token_hook), called
+// whenever Hydra issues tokens, and records the client as in use. It always
+// answers 204 so a database hiccup never fails a token request. The route
+// is public, so without TOKEN_HOOK_SECRET every call is refused.
+func (c *ClientReaper) HandleTokenHook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if c.hookToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.hookToken)) != 1 {
+		log.Printf("Rejected token hook call from %s", c.proxies.ClientIP(r))
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var hook tokenHookRequest
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		log.Printf("Failed to decode token hook payload: %v", err)
+	} else if hook.Request.ClientID != "" {
+		if err := c.store.RecordTokenIssued(hook.Request.ClientID); err != nil {
+			log.Printf("Failed to record token issued to %s: %v", hook.Request.ClientID, err)
+		}
+	}
+
+	// 204 tells Hydra to leave the token claims unchanged
+	w.WriteHeader(http.StatusNoContent)
+}
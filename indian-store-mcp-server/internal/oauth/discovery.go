@@ -0,0 +1,128 @@
+package oauth
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"indian-store-mcp-server/internal/config"
+)
+
+// DiscoveryHandler serves authorization server metadata (RFC 8414 and OIDC
+// discovery) built from Hydra's own openid-configuration, so the endpoints
+// clients see are the ones Hydra actually serves. Only what this server
+// adds on top of Hydra is merged in.
+type DiscoveryHandler struct {
+	oryClient *OryClient
+	ttl       time.Duration
+	document  map[string]interface{}
+	fetchedAt time.Time
+	mu        sync.Mutex
+}
+
+func NewDiscoveryHandler(cfg *config.Config, oryClient *OryClient) *DiscoveryHandler {
+	h := &DiscoveryHandler{
+		oryClient: oryClient,
+		ttl:       time.Duration(cfg.DiscoveryCacheTTL) * time.Second,
+	}
+
+	// Warm the cache; a failure here is retried on the first request
+	if _, err := h.hydraDocument(); err != nil {
+		log.Printf("Warning: could not load Hydra discovery document: %v", err)
+	}
+	return h
+}
+
+// hydraDocument returns Hydra's metadata, refetching it once the TTL has
+// passed. A stale copy is served while Hydra is unreachable.
+func (h *DiscoveryHandler) hydraDocument() (map[string]interface{}, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.document != nil && time.Since(h.fetchedAt) < h.ttl {
+		return h.document, nil
+	}
+
+	document, err := h.oryClient.FetchOpenIDConfiguration()
+	if err != nil {
+		if h.document != nil {
+			log.Printf("Serving cached discovery document, refresh failed: %v", err)
+			return h.document, nil
+		}
+		return nil, err
+	}
+
+	h.document = document
+	h.fetchedAt = time.Now()
+	return document, nil
+}
+
+// Issuer returns Hydra's issuer, falling back to this server's URL
+func (h *DiscoveryHandler) Issuer(r *http.Request) string {
+	if document, err := h.hydraDocument(); err == nil {
+		if issuer, ok := document["issuer"].(string); ok && issuer != "" {
+			return issuer
+		}
+	}
+	return BaseURL(r)
+}
+
+// HandleDiscovery serves /.well-known/oauth-authorization-server (including
+// RFC 8414 path-suffixed forms) and /.well-known/openid-configuration
+func (h *DiscoveryHandler) HandleDiscovery(w http.ResponseWriter, r *http.Request) {
+	hydra, err := h.hydraDocument()
+	if err != nil {
+		log.Printf("Discovery unavailable: %v", err)
+		w.Header().Set("Retry-After", "30")
+		jsonError(w, "temporarily_unavailable", "Authorization server metadata is unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Copy so the cached document is never modified
+	document := make(map[string]interface{}, len(hydra)+2)
+	for key, value := range hydra {
+		document[key] = value
+	}
+
+	// Hydra's dynamic registration is disabled; clients register through us
+	document["registration_endpoint"] = BaseURL(r) + "/oauth/register"
+	document["scopes_supported"] = mergeScopes(hydra["scopes_supported"], SupportedScopes)
+	// Hydra only accepts S256 (plain is disabled) and requires it from
+	// public clients
+	document["code_challenge_methods_supported"] = []string{"S256"}
+	// Revocation goes through us so cached introspection results are dropped
+	document["revocation_endpoint"] = BaseURL(r) + "/oauth/revoke"
+	// RP-initiated logout is Hydra's; it hands over to our /logout page
+	if _, ok := document["end_session_endpoint"]; !ok {
+		document["end_session_endpoint"] = h.Issuer(r) + "/oauth2/sessions/logout"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(document)
+}
+
+// mergeScopes returns Hydra's scopes followed by ours that it does not list
+func mergeScopes(hydraScopes interface{}, ours []string) []string {
+	var merged []string
+	seen := make(map[string]bool)
+
+	if list, ok := hydraScopes.([]interface{}); ok {
+		for _, scope := range list {
+			if s, ok := scope.(string); ok && !seen[s] {
+				seen[s] = true
+				merged = append(merged, s)
+			}
+		}
+	}
+	for _, s := range ours {
+		if !seen[s] {
+			seen[s] = true
+			merged = append(merged, s)
+		}
+	}
+	return merged
+}
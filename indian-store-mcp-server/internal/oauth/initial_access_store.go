@@ -0,0 +1,139 @@
+package oauth
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// errInvalidInitialAccessToken covers unknown, expired and used-up tokens
+var errInvalidInitialAccessToken = errors.New("invalid initial access token")
+
+// InitialAccessToken is an admin-issued permission to register clients
+// (RFC 7591 section 3). The token itself is only returned when issued.
+type InitialAccessToken struct {
+	ID          int        `json:"id"`
+	Token       string     `json:"token,omitempty"`
+	Description string     `json:"description"`
+	MaxUses     int        `json:"max_uses"` // 0 means unlimited
+	Uses        int        `json:"uses"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// InitialAccessTokenStore keeps initial access tokens in PostgreSQL. Only a
+// SHA-256 of each token is stored.
+type InitialAccessTokenStore struct {
+	db *sql.DB
+}
+
+// NewInitialAccessTokenStore creates the store on the shared database connection
+func NewInitialAccessTokenStore(db *sql.DB) (*InitialAccessTokenStore, error) {
+	store := &InitialAccessTokenStore{db: db}
+
+	// Create table if not exists
+	if err := store.createTable(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// createTable creates the oauth_initial_access_tokens table
+func (s *InitialAccessTokenStore) createTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS oauth_initial_access_tokens (
+		id SERIAL PRIMARY KEY,
+		token_hash CHAR(64) NOT NULL UNIQUE,
+		description VARCHAR(255) NOT NULL DEFAULT '',
+		max_uses INTEGER NOT NULL DEFAULT 1,
+		uses INTEGER NOT NULL DEFAULT 0,
+		expires_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`
+
+	_, err := s.db.Exec(query)
+	return err
+}
+
+// Issue creates a token allowing maxUses registrations (0 for unlimited)
+// that expires after ttl (0 for never)
+func (s *InitialAccessTokenStore) Issue(description string, maxUses int, ttl time.Duration) (*InitialAccessToken, error) {
+	token, err := newRegistrationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &InitialAccessToken{Token: token, Description: description, MaxUses: maxUses}
+	query := `
+	INSERT INTO oauth_initial_access_tokens (token_hash, description, max_uses, expires_at)
+	VALUES ($1, $2, $3, CASE WHEN $4::integer > 0 THEN NOW() + $4::integer * INTERVAL '1 second' END)
+	RETURNING id, expires_at, created_at`
+	var expiresAt sql.NullTime
+	err = s.db.QueryRow(query, hashRegistrationToken(token), description, maxUses, int(ttl.Seconds())).
+		Scan(&t.ID, &expiresAt, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		t.ExpiresAt = &expiresAt.Time
+	}
+	return t, nil
+}
+
+// List returns all tokens, newest first, without their secret values
+func (s *InitialAccessTokenStore) List() ([]InitialAccessToken, error) {
+	rows, err := s.db.Query(`
+	SELECT id, description, max_uses, uses, expires_at, created_at
+	FROM oauth_initial_access_tokens ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []InitialAccessToken{}
+	for rows.Next() {
+		var t InitialAccessToken
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.Description, &t.MaxUses, &t.Uses, &expiresAt, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			t.ExpiresAt = &expiresAt.Time
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// Consume uses up one registration of a token, atomically so concurrent
+// requests cannot exceed max_uses. It returns the token's ID for Release.
+func (s *InitialAccessTokenStore) Consume(token string) (int, error) {
+	var id int
+	err := s.db.QueryRow(`
+	UPDATE oauth_initial_access_tokens SET uses = uses + 1
+	WHERE token_hash = $1
+	  AND (expires_at IS NULL OR expires_at > NOW())
+	  AND (max_uses = 0 OR uses < max_uses)
+	RETURNING id`, hashRegistrationToken(token)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, errInvalidInitialAccessToken
+	}
+	return id, err
+}
+
+// Release gives back a use when the registration it was consumed for failed
+func (s *InitialAccessTokenStore) Release(id int) error {
+	_, err := s.db.Exec(`UPDATE oauth_initial_access_tokens SET uses = uses - 1 WHERE id = $1 AND uses > 0`, id)
+	return err
+}
+
+// Revoke deletes a token, reporting whether it existed
+func (s *InitialAccessTokenStore) Revoke(id int) (bool, error) {
+	result, err := s.db.Exec(`DELETE FROM oauth_initial_access_tokens WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,170 @@
+package oauth
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// IntrospectionCache keeps introspection results in memory so a busy client
+// does not cost one Hydra round trip per request. Entries are keyed by a
+// SHA-256 of the token, so raw tokens are never held.
+//
+// Active tokens are cached until the shorter of the TTL and their exp;
+// inactive ones for the (short) negative TTL. Errors are never cached. A
+// revoked token can therefore stay usable for up to one TTL.
+type IntrospectionCache struct {
+	introspect  func(token string) (*IntrospectionResponse, error)
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+
+	entries  map[string]*list.Element
+	lru      *list.List // front is most recently used
+	inflight map[string]*introspectionCall
+	mu       sync.Mutex
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	shared    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+type introspectionEntry struct {
+	key     string
+	resp    *IntrospectionResponse
+	expires time.Time
+}
+
+// introspectionCall is an upstream request other callers can wait on
+type introspectionCall struct {
+	done chan struct{}
+	resp *IntrospectionResponse
+	err  error
+}
+
+// IntrospectionCacheStats is a snapshot of the cache counters
+type IntrospectionCacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Shared    uint64 `json:"shared"` // misses answered by another request's upstream call
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+}
+
+func NewIntrospectionCache(introspect func(string) (*IntrospectionResponse, error), ttl, negativeTTL time.Duration, maxEntries int) *IntrospectionCache {
+	return &IntrospectionCache{
+		introspect:  introspect,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		maxEntries:  maxEntries,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		inflight:    make(map[string]*introspectionCall),
+	}
+}
+
+// Introspect returns the cached result for token or asks Hydra, sharing
+// one upstream call between concurrent requests for the same token
+func (c *IntrospectionCache) Introspect(token string) (*IntrospectionResponse, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*introspectionEntry)
+		if time.Now().Before(entry.expires) {
+			c.lru.MoveToFront(elem)
+			c.mu.Unlock()
+			c.hits.Add(1)
+			return entry.resp, nil
+		}
+		c.removeElement(elem)
+	}
+	c.misses.Add(1)
+
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		c.shared.Add(1)
+		return call.resp, call.err
+	}
+	call := &introspectionCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	call.resp, call.err = c.introspect(token)
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if call.err == nil {
+		c.store(key, call.resp)
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.resp, call.err
+}
+
+// Forget drops the cached result for token, e.g. after it was revoked
+func (c *IntrospectionCache) Forget(token string) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.removeElement(elem)
+	}
+	c.mu.Unlock()
+}
+
+// store caches resp; callers hold c.mu
+func (c *IntrospectionCache) store(key string, resp *IntrospectionResponse) {
+	now := time.Now()
+	expires := now.Add(c.negativeTTL)
+	if resp.Active {
+		expires = now.Add(c.ttl)
+		if resp.Exp > 0 {
+			if exp := time.Unix(resp.Exp, 0); exp.Before(expires) {
+				expires = exp
+			}
+		}
+	}
+	if !expires.After(now) {
+		return
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		c.removeElement(elem)
+	}
+	c.entries[key] = c.lru.PushFront(&introspectionEntry{key: key, resp: resp, expires: expires})
+
+	for c.lru.Len() > c.maxEntries {
+		c.removeElement(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// removeElement drops an entry; callers hold c.mu
+func (c *IntrospectionCache) removeElement(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*introspectionEntry).key)
+}
+
+// Stats returns the current counters
+func (c *IntrospectionCache) Stats() IntrospectionCacheStats {
+	c.mu.Lock()
+	size := c.lru.Len()
+	c.mu.Unlock()
+
+	return IntrospectionCacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Shared:    c.shared.Load(),
+		Evictions: c.evictions.Load(),
+		Size:      size,
+	}
+}
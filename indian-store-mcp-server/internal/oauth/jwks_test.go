@@ -0,0 +1,224 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"indian-store-mcp-server/internal/config"
+)
+
+// testKeys are signing keys generated for one test
+type testKeys struct {
+	rsa     *rsa.PrivateKey
+	ec256   *ecdsa.PrivateKey
+	ec384   *ecdsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKeys{rsa: rsaKey, ec256: ec256, ec384: ec384, ed25519: edKey}
+}
+
+// jwks publishes the public keys the way Hydra does
+func (k *testKeys) jwks() JSONWebKeySet {
+	b64 := base64.RawURLEncoding.EncodeToString
+	ecJWK := func(kid, crv string, key *ecdsa.PublicKey) JSONWebKey {
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return JSONWebKey{Kid: kid, Kty: "EC", Use: "sig", Crv: crv,
+			X: b64(key.X.FillBytes(make([]byte, size))), Y: b64(key.Y.FillBytes(make([]byte, size)))}
+	}
+	return JSONWebKeySet{Keys: []JSONWebKey{
+		{Kid: "rsa", Kty: "RSA", Use: "sig", N: b64(k.rsa.N.Bytes()), E: b64(big.NewInt(int64(k.rsa.E)).Bytes())},
+		ecJWK("ec256", "P-256", &k.ec256.PublicKey),
+		ecJWK("ec384", "P-384", &k.ec384.PublicKey),
+		{Kid: "ed25519", Kty: "OKP", Use: "sig", Crv: "Ed25519", X: b64(k.ed25519.Public().(ed25519.PublicKey))},
+	}}
+}
+
+// sign builds a compact JWS. The key is picked by kid, so a token can claim
+// one alg and be signed for another, or name a kid that does not exist.
+func (k *testKeys) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	b64 := base64.RawURLEncoding.EncodeToString
+	header, _ := json.Marshal(jwtHeader{Alg: alg, Kid: kid, Typ: "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+
+	var signature []byte
+	var err error
+	switch {
+	case alg == "none":
+	case kid == "ed25519":
+		signature = ed25519.Sign(k.ed25519, []byte(signed))
+	case strings.HasPrefix(kid, "ec"):
+		ecKey, hash := k.ec256, crypto.SHA256
+		if kid == "ec384" {
+			ecKey, hash = k.ec384, crypto.SHA384
+		}
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, ecKey, hashBytes(hash, []byte(signed)))
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+		}
+	case alg == "PS256":
+		signature, err = rsa.SignPSS(rand.Reader, k.rsa, crypto.SHA256, hashBytes(crypto.SHA256, []byte(signed)),
+			&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	default:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k.rsa, crypto.SHA256, hashBytes(crypto.SHA256, []byte(signed)))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + b64(signature)
+}
+
+// hydraJWKS serves keys at /.well-known/jwks.json and counts the fetches
+func hydraJWKS(t *testing.T, keys *JSONWebKeySet, fetches *int) *OryClient {
+	t.Helper()
+	hydra := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/jwks.json" {
+			http.NotFound(w, r)
+			return
+		}
+		*fetches++
+		json.NewEncoder(w).Encode(keys)
+	}))
+	t.Cleanup(hydra.Close)
+	return NewOryClient(&config.Config{OryURL: hydra.URL})
+}
+
+func TestParseJWK(t *testing.T) {
+	keys := newTestKeys(t)
+	for _, jwk := range keys.jwks().Keys {
+		t.Run(jwk.Kid, func(t *testing.T) {
+			if _, err := parseJWK(jwk); err != nil {
+				t.Errorf("parseJWK() error = %v", err)
+			}
+		})
+	}
+
+	offCurve := keys.jwks().Keys[1]
+	offCurve.Y = offCurve.X
+	invalid := map[string]JSONWebKey{
+		"symmetric key":     {Kid: "hmac", Kty: "oct"},
+		"unsupported curve": {Kid: "k1", Kty: "EC", Crv: "secp256k1"},
+		"point off curve":   offCurve,
+		"short Ed25519 key": {Kid: "ed", Kty: "OKP", Crv: "Ed25519", X: "AAAA"},
+		"empty modulus":     {Kid: "rsa", Kty: "RSA", E: "AQAB"},
+	}
+	for name, jwk := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseJWK(jwk); err == nil {
+				t.Error("parseJWK() accepted an invalid key")
+			}
+		})
+	}
+}
+
+func TestVerifyJWT(t *testing.T) {
+	keys := newTestKeys(t)
+	set := keys.jwks()
+	fetches := 0
+	cache := newJWKSCache(hydraJWKS(t, &set, &fetches), time.Hour)
+	claims := map[string]interface{}{"sub": "user@example.com"}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"RS256", keys.sign(t, "RS256", "rsa", claims), true},
+		{"PS256", keys.sign(t, "PS256", "rsa", claims), true},
+		{"ES256", keys.sign(t, "ES256", "ec256", claims), true},
+		{"ES384", keys.sign(t, "ES384", "ec384", claims), true},
+		{"EdDSA", keys.sign(t, "EdDSA", "ed25519", claims), true},
+		{"alg none", keys.sign(t, "none", "rsa", claims), false},
+		{"alg HS256", keys.sign(t, "HS256", "rsa", claims), false},
+		{"alg does not match key", keys.sign(t, "ES256", "rsa", claims), false},
+		{"unknown kid", keys.sign(t, "RS256", "other", claims), false},
+		{"not a JWT", "ory_at_abc.def", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifyJWT(tt.token, cache.key)
+			if tt.valid && (err != nil || got["sub"] != "user@example.com") {
+				t.Errorf("verifyJWT() = %v, %v; want the claims", got, err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("verifyJWT() accepted the token")
+			}
+		})
+	}
+
+	t.Run("tampered payload", func(t *testing.T) {
+		parts := strings.Split(keys.sign(t, "RS256", "rsa", claims), ".")
+		forged, _ := json.Marshal(map[string]interface{}{"sub": "admin@example.com"})
+		parts[1] = base64.RawURLEncoding.EncodeToString(forged)
+		if _, err := verifyJWT(strings.Join(parts, "."), cache.key); err == nil {
+			t.Error("verifyJWT() accepted a tampered payload")
+		}
+	})
+	t.Run("signed by another key", func(t *testing.T) {
+		other := newTestKeys(t)
+		if _, err := verifyJWT(other.sign(t, "EdDSA", "ed25519", claims), cache.key); err == nil {
+			t.Error("verifyJWT() accepted a foreign signature")
+		}
+	})
+}
+
+func TestJWKSCacheRefetchesOnRotation(t *testing.T) {
+	old, rotated := newTestKeys(t), newTestKeys(t)
+	set := old.jwks()
+	fetches := 0
+	cache := newJWKSCache(hydraJWKS(t, &set, &fetches), time.Hour)
+
+	if _, err := cache.key("rsa"); err != nil || fetches != 1 {
+		t.Fatalf("first key() = %v after %d fetches", err, fetches)
+	}
+	if _, err := cache.key("rsa"); err != nil || fetches != 1 {
+		t.Fatalf("cached key() = %v after %d fetches", err, fetches)
+	}
+
+	// Hydra rotates to a new kid: one refetch picks it up
+	set = rotated.jwks()
+	set.Keys[0].Kid = "rsa-2"
+	cache.lastAttempt = time.Time{}
+	if _, err := cache.key("rsa-2"); err != nil || fetches != 2 {
+		t.Fatalf("key() after rotation = %v after %d fetches", err, fetches)
+	}
+
+	// Made-up kids do not trigger another fetch within the refresh interval
+	if _, err := cache.key("made-up"); err != errUnknownKey || fetches != 2 {
+		t.Errorf("key(made-up) = %v after %d fetches, want errUnknownKey without a fetch", err, fetches)
+	}
+}
@@ -1,83 +1,94 @@
 package oauth
 
 import (
-	"bytes"
-	"crypto/rand"
-	"encoding/hex"
-	"encoding/json"
+	"crypto/subtle"
+	"fmt"
 	"html/template"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
-	"sync"
 	"time"
 
+	"indian-store-mcp-server/internal/config"
+	"indian-store-mcp-server/internal/lockout"
+	"indian-store-mcp-server/internal/mfa"
+	"indian-store-mcp-server/internal/sessions"
 	"indian-store-mcp-server/internal/users"
 )
 
-// Session represents a user session
-type Session struct {
-	Email     string
-	CreatedAt time.Time
-}
-
 // LoginConsentHandler handles login and consent flows
 type LoginConsentHandler struct {
-	oryClient  *OryClient
-	userStore  *users.UserStore
-	sessions   map[string]*Session
-	sessionMu  sync.RWMutex
+	oryClient          *OryClient
+	userStore          *users.UserStore
+	sessions           sessions.SessionStore
+	lockouts           *lockout.Store
+	mfa                *mfa.Store
+	mfaRequiredRoles   []string
+	firstParty         map[string]bool // client IDs that skip the consent page
+	consentRememberFor int
+	tokenAudience      string // added to every grant so JWT access tokens pass the aud check
+	proxies            *TrustedProxies
 }
 
-func NewLoginConsentHandler(oryClient *OryClient, userStore *users.UserStore) *LoginConsentHandler {
+func NewLoginConsentHandler(cfg *config.Config, oryClient *OryClient, userStore *users.UserStore, sessionStore sessions.SessionStore, lockouts *lockout.Store, mfaStore *mfa.Store) *LoginConsentHandler {
+	firstParty := make(map[string]bool)
+	for _, clientID := range cfg.FirstPartyClients {
+		firstParty[clientID] = true
+	}
 	return &LoginConsentHandler{
-		oryClient: oryClient,
-		userStore: userStore,
-		sessions:  make(map[string]*Session),
+		oryClient:          oryClient,
+		userStore:          userStore,
+		sessions:           sessionStore,
+		lockouts:           lockouts,
+		mfa:                mfaStore,
+		mfaRequiredRoles:   cfg.MFARequiredRoles,
+		firstParty:         firstParty,
+		consentRememberFor: cfg.ConsentRememberFor,
+		tokenAudience:      cfg.TokenAudience,
+		proxies:            NewTrustedProxies(cfg),
 	}
 }
 
-// generateSessionID creates a random session ID
-func (h *LoginConsentHandler) generateSessionID() string {
-	b := make([]byte, 32)
-	rand.Read(b)
-	return hex.EncodeToString(b)
+// getSession retrieves a session by cookie
+func (h *LoginConsentHandler) getSession(r *http.Request) (*sessions.Session, bool) {
+	return sessionFromRequest(h.sessions, r)
 }
 
-// getSession retrieves a session by cookie
-func (h *LoginConsentHandler) getSession(r *http.Request) (*Session, bool) {
+// sessionFromRequest loads the login session named by the session_id cookie
+func sessionFromRequest(store sessions.SessionStore, r *http.Request) (*sessions.Session, bool) {
 	cookie, err := r.Cookie("session_id")
 	if err != nil {
 		return nil, false
 	}
 
-	h.sessionMu.RLock()
-	defer h.sessionMu.RUnlock()
-
-	session, exists := h.sessions[cookie.Value]
-	if !exists {
-		return nil, false
-	}
-
-	// Check if session is expired (24 hours)
-	if time.Since(session.CreatedAt) > 24*time.Hour {
+	session, err := store.Get(cookie.Value)
+	if err != nil {
+		if err != sessions.ErrNotFound {
+			log.Printf("Error loading session: %v", err)
+		}
 		return nil, false
 	}
 
 	return session, true
 }
 
-// createSession creates a new session
-func (h *LoginConsentHandler) createSession(w http.ResponseWriter, email string) string {
-	sessionID := h.generateSessionID()
+// createSession starts a new session. Any session the browser already had
+// is ended first, so the ID always changes on login (no session fixation).
+func (h *LoginConsentHandler) createSession(w http.ResponseWriter, r *http.Request, email string, amr []string) error {
+	if cookie, err := r.Cookie("session_id"); err == nil {
+		if err := h.sessions.Delete(cookie.Value); err != nil {
+			log.Printf("Error ending previous session: %v", err)
+		}
+	}
 
-	h.sessionMu.Lock()
-	h.sessions[sessionID] = &Session{
-		Email:     email,
-		CreatedAt: time.Now(),
+	sessionID, session, err := h.sessions.Create(email, sessions.Metadata{
+		UserAgent: r.UserAgent(),
+		IP:        h.proxies.ClientIP(r),
+		AMR:       amr,
+	})
+	if err != nil {
+		return err
 	}
-	h.sessionMu.Unlock()
 
 	http.SetCookie(w, &http.Cookie{
 		Name:     "session_id",
@@ -86,27 +97,48 @@ func (h *LoginConsentHandler) createSession(w http.ResponseWriter, email string)
 		HttpOnly: true,
 		Secure:   true,
 		SameSite: http.SameSiteLaxMode,
-		MaxAge:   86400, // 24 hours
+		MaxAge:   int(time.Until(session.ExpiresAt).Seconds()),
 	})
 
-	return sessionID
+	return nil
 }
 
 // HandleLogin handles the login page
+// Without a login_challenge it signs the user in to the account pages.
 func (h *LoginConsentHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	challenge := r.URL.Query().Get("login_challenge")
-	if challenge == "" {
-		http.Error(w, "Missing login_challenge", http.StatusBadRequest)
-		return
+	if challenge != "" {
+		log.Printf("Login challenge received: %s", challenge)
 	}
 
-	log.Printf("Login challenge received: %s", challenge)
-
 	// Check if user already has a session
 	if session, exists := h.getSession(r); exists {
+		// Sessions from before the user turned on (or was required to
+		// use) 2FA have to step up first
+		if !contains(session.AMR, amrOTP) {
+			needed, err := h.secondFactorNeeded(session.Email)
+			if err != nil {
+				log.Printf("Error checking 2FA of %s: %v", session.Email, err)
+				http.Error(w, "Internal error", http.StatusInternalServerError)
+				return
+			}
+			if needed {
+				h.startSecondFactor(w, r, challenge, session.Email)
+				return
+			}
+		}
+
+		if challenge == "" {
+			http.Redirect(w, r, accountAppsPath, http.StatusFound)
+			return
+		}
 		// User is already logged in, accept the login automatically
 		log.Printf("User %s already logged in, auto-accepting", session.Email)
-		h.acceptLogin(w, r, challenge, session.Email)
+		amr := session.AMR
+		if len(amr) == 0 {
+			amr = []string{amrPassword}
+		}
+		h.acceptLogin(w, r, challenge, session.Email, amr)
 		return
 	}
 
@@ -115,22 +147,54 @@ func (h *LoginConsentHandler) HandleLogin(w http.ResponseWriter, r *http.Request
 		r.ParseForm()
 		email := r.FormValue("email")
 		password := r.FormValue("password")
+		attempt := lockout.Attempt{Email: email, IP: h.proxies.LockoutIP(r), UserAgent: r.UserAgent()}
+
+		// Locked accounts and IPs are refused before the password is checked
+		wait, err := h.lockouts.Check(attempt)
+		if err != nil {
+			log.Printf("Error checking login lockout: %v", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		if wait > 0 {
+			h.showLoginForm(w, challenge, lockedMessage(wait))
+			return
+		}
 
 		// Authenticate user
 		user, err := h.userStore.Authenticate(email, password)
-		if err != nil {
-			log.Printf("Authentication failed for %s: %v", email, err)
+		if err == users.ErrInvalidCredentials {
+			wait, err := h.lockouts.RecordFailure(attempt)
+			if err != nil {
+				log.Printf("Error recording failed login: %v", err)
+			}
+			if wait > 0 {
+				h.showLoginForm(w, challenge, lockedMessage(wait))
+				return
+			}
 			h.showLoginForm(w, challenge, "Invalid email or password")
 			return
 		}
+		if err != nil {
+			log.Printf("Authentication error for %s: %v", email, err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
 
 		log.Printf("User %s authenticated successfully", user.Email)
 
-		// Create session
-		h.createSession(w, user.Email)
+		needed, err := h.secondFactorNeeded(user.Email)
+		if err != nil {
+			log.Printf("Error checking 2FA of %s: %v", user.Email, err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		if needed {
+			h.startSecondFactor(w, r, challenge, user.Email)
+			return
+		}
 
-		// Accept the login
-		h.acceptLogin(w, r, challenge, user.Email)
+		h.completeLogin(w, r, challenge, user.Email, []string{amrPassword})
 		return
 	}
 
@@ -235,7 +299,7 @@ func (h *LoginConsentHandler) showLoginForm(w http.ResponseWriter, challenge, er
 <body>
     <div class="login-container">
         <h1>Indian Store MCP</h1>
-        <p class="subtitle">Sign in to authorize access</p>
+        <p class="subtitle">{{if .Challenge}}Sign in to authorize access{{else}}Sign in to manage your connected apps{{end}}</p>
         
         {{if .Error}}
         <div class="error">{{.Error}}</div>
@@ -272,54 +336,63 @@ func (h *LoginConsentHandler) showLoginForm(w http.ResponseWriter, challenge, er
 	})
 }
 
-// acceptLogin accepts the login with Hydra
-func (h *LoginConsentHandler) acceptLogin(w http.ResponseWriter, r *http.Request, challenge, userEmail string) {
-	acceptData := map[string]interface{}{
-		"subject":      userEmail,
-		"remember":     true,
-		"remember_for": 86400, // 24 hours
+// completeLogin finishes a login once every factor it needs was checked:
+// it clears the failed attempts, starts the session and hands the user to
+// Hydra (or, without a login_challenge, to the account pages)
+func (h *LoginConsentHandler) completeLogin(w http.ResponseWriter, r *http.Request, challenge, email string, amr []string) {
+	attempt := lockout.Attempt{Email: email, IP: h.proxies.LockoutIP(r), UserAgent: r.UserAgent()}
+	if err := h.lockouts.RecordSuccess(attempt); err != nil {
+		log.Printf("Error clearing failed logins of %s: %v", email, err)
 	}
 
-	acceptBody, _ := json.Marshal(acceptData)
-	acceptReq, err := http.NewRequest("PUT",
-		h.oryClient.config.OryAdminURL+"/admin/oauth2/auth/requests/login/accept?login_challenge="+challenge,
-		bytes.NewReader(acceptBody))
-	if err != nil {
-		log.Printf("Error creating accept request: %v", err)
+	// Create session
+	if err := h.createSession(w, r, email, amr); err != nil {
+		log.Printf("Error creating session for %s: %v", email, err)
 		http.Error(w, "Internal error", http.StatusInternalServerError)
 		return
 	}
-	acceptReq.Header.Set("Content-Type", "application/json")
 
-	acceptResp, err := h.oryClient.client.Do(acceptReq)
-	if err != nil {
-		log.Printf("Error accepting login: %v", err)
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+	if challenge == "" {
+		http.Redirect(w, r, accountAppsPath, http.StatusFound)
 		return
 	}
-	defer acceptResp.Body.Close()
 
-	if acceptResp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(acceptResp.Body)
-		log.Printf("Error accepting login: %s", string(body))
-		http.Error(w, "Error completing login", http.StatusInternalServerError)
-		return
+	// Accept the login
+	h.acceptLogin(w, r, challenge, email, amr)
+}
+
+// lockedMessage tells a locked-out user when to try again
+func lockedMessage(wait time.Duration) string {
+	minutes := int((wait + time.Minute - 1) / time.Minute)
+	if minutes == 1 {
+		return "Too many failed sign-in attempts. Try again in 1 minute."
 	}
+	return fmt.Sprintf("Too many failed sign-in attempts. Try again in %d minutes.", minutes)
+}
 
-	var acceptResult struct {
-		RedirectTo string `json:"redirect_to"`
+// acceptLogin accepts the login with Hydra. amr lists the authentication
+// methods used (RFC 8176) and ends up in the ID token.
+func (h *LoginConsentHandler) acceptLogin(w http.ResponseWriter, r *http.Request, challenge, userEmail string, amr []string) {
+	acceptData := map[string]interface{}{
+		"subject":      userEmail,
+		"remember":     true,
+		"remember_for": 86400, // 24 hours
+		"amr":          amr,
 	}
-	if err := json.NewDecoder(acceptResp.Body).Decode(&acceptResult); err != nil {
-		log.Printf("Error decoding accept response: %v", err)
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+
+	redirectTo, err := h.oryClient.AcceptLoginRequest(challenge, acceptData)
+	if err != nil {
+		log.Printf("Error accepting login: %v", err)
+		http.Error(w, "Error completing login", http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("Login accepted for %s, redirecting to: %s", userEmail, acceptResult.RedirectTo)
-	http.Redirect(w, r, acceptResult.RedirectTo, http.StatusFound)
+	log.Printf("Login accepted for %s, redirecting to: %s", userEmail, redirectTo)
+	http.Redirect(w, r, redirectTo, http.StatusFound)
 }
 
-// HandleConsent handles the consent page
+// HandleConsent shows the consent page for a client's authorization request.
+// First-party clients and grants the user asked Hydra to remember skip it.
 func (h *LoginConsentHandler) HandleConsent(w http.ResponseWriter, r *http.Request) {
 	challenge := r.URL.Query().Get("consent_challenge")
 	if challenge == "" {
@@ -330,55 +403,116 @@ func (h *LoginConsentHandler) HandleConsent(w http.ResponseWriter, r *http.Reque
 	log.Printf("Consent challenge received: %s", challenge)
 
 	// Get consent request info from Hydra
-	req, err := http.NewRequest("GET", h.oryClient.config.OryAdminURL+"/admin/oauth2/auth/requests/consent?consent_challenge="+challenge, nil)
+	consentReq, err := h.oryClient.GetConsentRequest(challenge)
 	if err != nil {
-		log.Printf("Error creating request: %v", err)
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+		log.Printf("Error getting consent request: %v", err)
+		http.Error(w, "Error communicating with OAuth server", http.StatusInternalServerError)
 		return
 	}
 
-	resp, err := h.oryClient.client.Do(req)
-	if err != nil {
-		log.Printf("Error getting consent request: %v", err)
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+	// Get user info from session
+	user, exists := h.userStore.GetUser(consentReq.Subject)
+	if !exists {
+		log.Printf("User not found: %s", consentReq.Subject)
+		http.Error(w, "User not found", http.StatusUnauthorized)
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Error from Hydra: %s", string(body))
-		http.Error(w, "Error communicating with OAuth server", http.StatusInternalServerError)
+	clientID := consentReq.Client.ClientID
+	if !hasPKCE(consentReq.Client.TokenEndpointAuthMethod, consentReq.RequestURL) {
+		log.Printf("Rejected authorization of public client %s without PKCE S256", clientID)
+		redirectTo, err := h.oryClient.RejectConsentRequest(challenge, "invalid_request",
+			"Public clients must use PKCE with code_challenge_method S256")
+		if err != nil {
+			log.Printf("Error rejecting consent: %v", err)
+			http.Error(w, "Error completing consent", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, redirectTo, http.StatusFound)
 		return
 	}
 
-	var consentInfo struct {
-		RequestedScope []string `json:"requested_scope"`
-		Subject        string   `json:"subject"`
-		Client         struct {
-			ClientID string `json:"client_id"`
-		} `json:"client"`
+	switch {
+	case h.firstParty[clientID]:
+		log.Printf("Client %s is first-party, granting consent for %s", clientID, user.Email)
+		h.acceptConsent(w, r, challenge, consentReq, user, consentReq.RequestedScope, false)
+		return
+	case consentReq.Skip:
+		// Hydra only skips when a remembered grant covers every requested scope
+		log.Printf("Using remembered consent of %s for client %s", user.Email, clientID)
+		h.acceptConsent(w, r, challenge, consentReq, user, consentReq.RequestedScope, false)
+		return
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&consentInfo); err != nil {
-		log.Printf("Error decoding consent info: %v", err)
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+
+	// Only the signed-in user the request is for may answer it, and only
+	// from our own form
+	session, ok := h.getSession(r)
+	if !ok || session.Email != consentReq.Subject {
+		log.Printf("Consent for %s requested without their session", consentReq.Subject)
+		http.Error(w, "Your session has expired, please sign in again", http.StatusForbidden)
 		return
 	}
 
-	// Get user info from session
-	user, exists := h.userStore.GetUser(consentInfo.Subject)
-	if !exists {
-		log.Printf("User not found: %s", consentInfo.Subject)
-		http.Error(w, "User not found", http.StatusUnauthorized)
+	if r.Method != "POST" {
+		h.showConsentForm(w, consentReq, accountCSRFToken(r))
 		return
 	}
 
-	// Auto-accept the consent with user information
+	r.ParseForm()
+	if subtle.ConstantTimeCompare([]byte(r.FormValue("csrf")), []byte(accountCSRFToken(r))) != 1 {
+		http.Error(w, "Invalid form submission", http.StatusForbidden)
+		return
+	}
+	if r.FormValue("action") != "allow" {
+		redirectTo, err := h.oryClient.RejectConsentRequest(challenge, "access_denied", "The user denied the request")
+		if err != nil {
+			log.Printf("Error rejecting consent: %v", err)
+			http.Error(w, "Error completing consent", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("User %s denied consent to client %s", user.Email, clientID)
+		http.Redirect(w, r, redirectTo, http.StatusFound)
+		return
+	}
+
+	// Grant the checked scopes; only requested ones count and required
+	// ones cannot be dropped by editing the form
+	checked := make(map[string]bool)
+	for _, scope := range r.Form["scope"] {
+		checked[scope] = true
+	}
+	var granted []string
+	for _, scope := range consentReq.RequestedScope {
+		if checked[scope] || requiredConsentScopes[scope] {
+			granted = append(granted, scope)
+		}
+	}
+
+	log.Printf("User %s granted %v to client %s", user.Email, granted, clientID)
+	h.acceptConsent(w, r, challenge, consentReq, user, granted, r.FormValue("remember") == "on")
+}
+
+// acceptConsent grants scopes to the client with Hydra. remember makes Hydra
+// skip the consent page next time for the same (or fewer) scopes.
+func (h *LoginConsentHandler) acceptConsent(w http.ResponseWriter, r *http.Request, challenge string, consentReq *ConsentRequest, user *users.User, granted []string, remember bool) {
+	if granted == nil {
+		granted = []string{}
+	}
+	audience := []string{}
+	for _, aud := range consentReq.RequestedAudience {
+		if aud != h.tokenAudience {
+			audience = append(audience, aud)
+		}
+	}
+	if h.tokenAudience != "" {
+		audience = append(audience, h.tokenAudience)
+	}
+
 	acceptData := map[string]interface{}{
-		"grant_scope": consentInfo.RequestedScope,
-		"grant_access_token_audience": []string{},
-		"remember": true,
-		"remember_for": 86400, // 24 hours
+		"grant_scope":                 granted,
+		"grant_access_token_audience": audience,
+		"remember":                    remember,
+		"remember_for":                h.consentRememberFor,
 		"session": map[string]interface{}{
 			"id_token": map[string]interface{}{
 				"email": user.Email,
@@ -387,43 +521,171 @@ func (h *LoginConsentHandler) HandleConsent(w http.ResponseWriter, r *http.Reque
 		},
 	}
 
-	acceptBody, _ := json.Marshal(acceptData)
-	acceptReq, err := http.NewRequest("PUT",
-		h.oryClient.config.OryAdminURL+"/admin/oauth2/auth/requests/consent/accept?consent_challenge="+challenge,
-		bytes.NewReader(acceptBody))
+	redirectTo, err := h.oryClient.AcceptConsentRequest(challenge, acceptData)
 	if err != nil {
-		log.Printf("Error creating accept request: %v", err)
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+		log.Printf("Error accepting consent: %v", err)
+		http.Error(w, "Error completing consent", http.StatusInternalServerError)
 		return
 	}
-	acceptReq.Header.Set("Content-Type", "application/json")
 
-	acceptResp, err := h.oryClient.client.Do(acceptReq)
-	if err != nil {
-		log.Printf("Error accepting consent: %v", err)
-		http.Error(w, "Internal error", http.StatusInternalServerError)
-		return
+	log.Printf("Consent accepted, redirecting to: %s", redirectTo)
+	http.Redirect(w, r, redirectTo, http.StatusFound)
+}
+
+// consentScope is a row on the consent page
+type consentScope struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// showConsentForm displays the consent page
+func (h *LoginConsentHandler) showConsentForm(w http.ResponseWriter, consentReq *ConsentRequest, csrf string) {
+	tmpl := `<!DOCTYPE html>
+<html>
+<head>
+    <title>Indian Store MCP - Authorize {{.ClientName}}</title>
+    <style>
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            min-height: 100vh;
+            display: flex;
+            align-items: center;
+            justify-content: center;
+            margin: 0;
+        }
+        .consent-container {
+            background: white;
+            padding: 40px;
+            border-radius: 10px;
+            box-shadow: 0 10px 40px rgba(0,0,0,0.2);
+            width: 100%;
+            max-width: 440px;
+        }
+        .client { display: flex; align-items: center; margin-bottom: 20px; }
+        .client img { width: 48px; height: 48px; border-radius: 8px; margin-right: 15px; object-fit: contain; }
+        h1 { color: #333; margin: 0; font-size: 22px; }
+        .subtitle { color: #666; margin: 4px 0 0 0; font-size: 13px; }
+        .scopes { list-style: none; padding: 0; margin: 20px 0; }
+        .scopes li { padding: 10px 0; border-bottom: 1px solid #eee; font-size: 14px; color: #333; }
+        .scopes label { display: flex; align-items: flex-start; cursor: pointer; }
+        .scopes input { margin: 3px 10px 0 0; }
+        .scope-name { display: block; color: #999; font-size: 12px; font-family: monospace; }
+        .remember { font-size: 13px; color: #555; margin-bottom: 20px; }
+        .buttons { display: flex; gap: 10px; }
+        button {
+            flex: 1;
+            padding: 12px;
+            border: none;
+            border-radius: 5px;
+            font-size: 16px;
+            font-weight: 600;
+            cursor: pointer;
+        }
+        .allow { background: #667eea; color: white; }
+        .allow:hover { background: #5568d3; }
+        .deny { background: #eee; color: #333; }
+        .links { margin-top: 20px; font-size: 12px; color: #666; }
+        .links a { color: #667eea; margin-right: 10px; }
+    </style>
+</head>
+<body>
+    <div class="consent-container">
+        <div class="client">
+            {{if .LogoURI}}<img src="{{.LogoURI}}" alt="">{{end}}
+            <div>
+                <h1>{{.ClientName}} wants to access your account</h1>
+                {{if .RedirectHost}}<p class="subtitle">You will be sent back to <strong>{{.RedirectHost}}</strong></p>{{end}}
+            </div>
+        </div>
+
+        <form method="POST">
+            <input type="hidden" name="csrf" value="{{.CSRF}}">
+            <ul class="scopes">
+            {{range .Scopes}}
+                <li>
+                    <label>
+                        <input type="checkbox" name="scope" value="{{.Name}}" checked {{if .Required}}disabled{{end}}>
+                        <span>{{.Description}}{{if .Required}} (required){{end}}<span class="scope-name">{{.Name}}</span></span>
+                    </label>
+                </li>
+            {{end}}
+            </ul>
+
+            <label class="remember">
+                <input type="checkbox" name="remember" checked> Remember this decision
+            </label>
+
+            <div class="buttons">
+                <button class="deny" type="submit" name="action" value="deny">Deny</button>
+                <button class="allow" type="submit" name="action" value="allow">Allow</button>
+            </div>
+        </form>
+
+        {{if or .PolicyURI .TosURI}}
+        <div class="links">
+            {{if .PolicyURI}}<a href="{{.PolicyURI}}" target="_blank" rel="noopener">Privacy policy</a>{{end}}
+            {{if .TosURI}}<a href="{{.TosURI}}" target="_blank" rel="noopener">Terms of service</a>{{end}}
+        </div>
+        {{end}}
+    </div>
+</body>
+</html>`
+
+	client := consentReq.Client
+	clientName := client.ClientName
+	if clientName == "" {
+		clientName = client.ClientID
 	}
-	defer acceptResp.Body.Close()
 
-	if acceptResp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(acceptResp.Body)
-		log.Printf("Error accepting consent: %s", string(body))
-		http.Error(w, "Error completing consent", http.StatusInternalServerError)
-		return
+	var scopes []consentScope
+	for _, scope := range consentReq.RequestedScope {
+		scopes = append(scopes, consentScope{
+			Name:        scope,
+			Description: ScopeDescription(scope),
+			Required:    requiredConsentScopes[scope],
+		})
 	}
 
-	var acceptResult struct {
-		RedirectTo string `json:"redirect_to"`
+	t := template.Must(template.New("consent").Parse(tmpl))
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	// Clients cannot frame the page to trick users into clicking Allow
+	w.Header().Set("X-Frame-Options", "DENY")
+	t.Execute(w, map[string]interface{}{
+		"ClientName":   clientName,
+		"LogoURI":      httpsURL(client.LogoURI),
+		"PolicyURI":    httpsURL(client.PolicyURI),
+		"TosURI":       httpsURL(client.TosURI),
+		"RedirectHost": consentRedirectHost(consentReq),
+		"Scopes":       scopes,
+		"CSRF":         csrf,
+	})
+}
+
+// consentRedirectHost is the host the authorization response goes to: the
+// redirect_uri of the original request, else the client's first one
+func consentRedirectHost(consentReq *ConsentRequest) string {
+	redirectURI := ""
+	if requestURL, err := url.Parse(consentReq.RequestURL); err == nil {
+		redirectURI = requestURL.Query().Get("redirect_uri")
 	}
-	if err := json.NewDecoder(acceptResp.Body).Decode(&acceptResult); err != nil {
-		log.Printf("Error decoding accept response: %v", err)
-		http.Error(w, "Internal error", http.StatusInternalServerError)
-		return
+	if redirectURI == "" && len(consentReq.Client.RedirectURIs) > 0 {
+		redirectURI = consentReq.Client.RedirectURIs[0]
+	}
+	if u, err := url.Parse(redirectURI); err == nil {
+		return u.Host
 	}
+	return ""
+}
 
-	log.Printf("Consent accepted, redirecting to: %s", acceptResult.RedirectTo)
-	http.Redirect(w, r, acceptResult.RedirectTo, http.StatusFound)
+// httpsURL returns rawURL if it is an absolute https URL, else ""
+func httpsURL(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Scheme == "https" && u.Host != "" {
+		return rawURL
+	}
+	return ""
 }
 
 // HandleError handles OAuth error redirects
@@ -472,3 +734,147 @@ func (h *LoginConsentHandler) HandleError(w http.ResponseWriter, r *http.Request
 		"ErrorDescription": errorDesc,
 	})
 }
+
+// destroySession removes the caller's server-side session and clears the cookie
+func (h *LoginConsentHandler) destroySession(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie("session_id"); err == nil {
+		if err := h.sessions.Delete(cookie.Value); err != nil {
+			log.Printf("Error deleting session: %v", err)
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session_id",
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+}
+
+// HandleLogout handles Hydra's logout flow (urls.logout). Without a
+// logout_challenge it asks the signed-in user to confirm, then ends our
+// session and starts Hydra's logout, which comes back here with a
+// challenge. A GET never signs anyone out, so a forged link cannot either.
+func (h *LoginConsentHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	challenge := r.URL.Query().Get("logout_challenge")
+	if challenge == "" {
+		session, ok := h.getSession(r)
+		if !ok {
+			http.Redirect(w, r, BaseURL(r)+"/oauth2/sessions/logout", http.StatusFound)
+			return
+		}
+		logoutReq := &LogoutRequest{Subject: session.Email}
+		if r.Method != "POST" {
+			h.showLogoutPage(w, "confirm", logoutReq)
+			return
+		}
+
+		// The session cookie is SameSite=Lax, so a cross-site POST arrives
+		// without it and never gets here
+		r.ParseForm()
+		if r.FormValue("action") != "logout" {
+			h.showLogoutPage(w, "cancelled", logoutReq)
+			return
+		}
+		h.destroySession(w, r)
+		log.Printf("User %s signed out", session.Email)
+		http.Redirect(w, r, BaseURL(r)+"/oauth2/sessions/logout", http.StatusFound)
+		return
+	}
+
+	logoutReq, err := h.oryClient.GetLogoutRequest(challenge)
+	if err != nil {
+		log.Printf("Error getting logout request: %v", err)
+		http.Error(w, "Invalid or expired logout request", http.StatusBadRequest)
+		return
+	}
+
+	// A relying party that proved the session with id_token_hint needs no
+	// confirmation; anything else could be a forged logout link
+	if r.Method == "POST" || logoutReq.RPInitiated {
+		r.ParseForm()
+		if r.Method == "POST" && r.FormValue("action") != "logout" {
+			if err := h.oryClient.RejectLogoutRequest(challenge); err != nil {
+				log.Printf("Error rejecting logout: %v", err)
+			}
+			log.Printf("Logout cancelled by %s", logoutReq.Subject)
+			h.showLogoutPage(w, "cancelled", logoutReq)
+			return
+		}
+
+		h.destroySession(w, r)
+		redirectTo, err := h.oryClient.AcceptLogoutRequest(challenge)
+		if err != nil {
+			log.Printf("Error accepting logout: %v", err)
+			http.Error(w, "Error completing logout", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("User %s logged out, redirecting to: %s", logoutReq.Subject, redirectTo)
+		http.Redirect(w, r, redirectTo, http.StatusFound)
+		return
+	}
+
+	h.showLogoutPage(w, "confirm", logoutReq)
+}
+
+// HandleLoggedOut is where Hydra sends the browser after logout
+// (urls.post_logout_redirect)
+func (h *LoginConsentHandler) HandleLoggedOut(w http.ResponseWriter, r *http.Request) {
+	h.showLogoutPage(w, "done", nil)
+}
+
+// showLogoutPage renders the logout confirmation and result pages
+func (h *LoginConsentHandler) showLogoutPage(w http.ResponseWriter, state string, logoutReq *LogoutRequest) {
+	tmpl := `<!DOCTYPE html>
+<html>
+<head>
+    <title>Indian Store MCP - Sign out</title>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 420px; margin: 80px auto; padding: 20px; color: #333; }
+        .box { border: 1px solid #e0e0e0; border-radius: 10px; padding: 30px; }
+        h1 { font-size: 22px; margin: 0 0 15px 0; }
+        button { padding: 10px 18px; border-radius: 5px; border: none; font-size: 15px; cursor: pointer; margin-right: 8px; }
+        .primary { background: #667eea; color: white; }
+        .secondary { background: #eee; color: #333; }
+    </style>
+</head>
+<body>
+    <div class="box">
+    {{if eq .State "confirm"}}
+        <h1>Sign out?</h1>
+        <p>You are signed in as <strong>{{.Subject}}</strong>{{if .Client}} and {{.Client}} asked to sign you out{{end}}.</p>
+        <form method="POST">
+            <button class="primary" type="submit" name="action" value="logout">Sign out</button>
+            <button class="secondary" type="submit" name="action" value="cancel">Stay signed in</button>
+        </form>
+    {{else if eq .State "cancelled"}}
+        <h1>Still signed in</h1>
+        <p>You are still signed in as <strong>{{.Subject}}</strong>. You can close this window.</p>
+    {{else}}
+        <h1>Signed out</h1>
+        <p>You have been signed out. You can close this window.</p>
+    {{end}}
+    </div>
+</body>
+</html>`
+
+	data := map[string]string{"State": state}
+	if logoutReq != nil {
+		data["Subject"] = logoutReq.Subject
+		if logoutReq.Client != nil {
+			data["Client"] = logoutReq.Client.ClientName
+			if data["Client"] == "" {
+				data["Client"] = logoutReq.Client.ClientID
+			}
+		}
+	}
+
+	t := template.Must(template.New("logout").Parse(tmpl))
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	t.Execute(w, data)
+}
@@ -0,0 +1,303 @@
+package oauth
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"indian-store-mcp-server/internal/lockout"
+	"indian-store-mcp-server/internal/mfa"
+)
+
+// Authentication method references (RFC 8176) passed to Hydra as amr
+const (
+	amrPassword = "pwd"
+	amrOTP      = "otp"
+	amrMFA      = "mfa"
+)
+
+// mfaCookie carries a login between the password and the second factor
+const mfaCookie = "mfa_challenge"
+
+// secondFactorNeeded reports whether a user has to pass TOTP to sign in:
+// they turned 2FA on, or their role is in MFA_REQUIRED_ROLES
+func (h *LoginConsentHandler) secondFactorNeeded(email string) (bool, error) {
+	enabled, err := h.mfa.Enabled(email)
+	if err != nil || enabled {
+		return enabled, err
+	}
+	user, ok := h.userStore.GetUser(email)
+	return ok && contains(h.mfaRequiredRoles, user.Role), nil
+}
+
+// startSecondFactor parks a login that passed the password check and sends
+// the browser to /login/mfa
+func (h *LoginConsentHandler) startSecondFactor(w http.ResponseWriter, r *http.Request, challenge, email string) {
+	id, err := h.mfa.StartChallenge(email, challenge)
+	if err != nil {
+		log.Printf("Error starting 2FA for %s: %v", email, err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     mfaCookie,
+		Value:    id,
+		Path:     "/login",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   300,
+	})
+	http.Redirect(w, r, "/login/mfa", http.StatusFound)
+}
+
+// HandleLoginMFA is the second login step. Users with 2FA enter a TOTP or
+// recovery code; users whose role requires 2FA but who have not set it up
+// enroll here before the login completes.
+func (h *LoginConsentHandler) HandleLoginMFA(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(mfaCookie)
+	if err != nil {
+		renderMFAPage(w, mfaPage{State: "expired"})
+		return
+	}
+	pending, err := h.mfa.Challenge(cookie.Value)
+	if err == mfa.ErrChallengeNotFound {
+		h.endSecondFactor(w, cookie.Value)
+		renderMFAPage(w, mfaPage{State: "expired"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading 2FA challenge: %v", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	// Enrolled during this login and shown the recovery codes
+	if pending.Verified {
+		h.endSecondFactor(w, cookie.Value)
+		h.completeLogin(w, r, pending.LoginChallenge, pending.Email, []string{amrPassword, amrOTP, amrMFA})
+		return
+	}
+
+	enabled, err := h.mfa.Enabled(pending.Email)
+	if err != nil {
+		log.Printf("Error checking 2FA of %s: %v", pending.Email, err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	page := mfaPage{State: "verify", Email: pending.Email, FormAction: "/login/mfa"}
+	if r.Method == http.MethodPost {
+		r.ParseForm()
+		attempt := lockout.Attempt{Email: pending.Email, IP: h.proxies.LockoutIP(r), UserAgent: r.UserAgent()}
+		if wait, err := h.lockouts.Check(attempt); err != nil || wait > 0 {
+			if err != nil {
+				log.Printf("Error checking login lockout: %v", err)
+				http.Error(w, "Internal error", http.StatusInternalServerError)
+				return
+			}
+			h.endSecondFactor(w, cookie.Value)
+			renderMFAPage(w, mfaPage{State: "expired", Error: lockedMessage(wait)})
+			return
+		}
+
+		code := r.FormValue("code")
+		if enabled {
+			method, err := h.mfa.Verify(pending.Email, code)
+			if err == nil {
+				log.Printf("User %s passed 2FA (%s)", pending.Email, method)
+				h.endSecondFactor(w, cookie.Value)
+				h.completeLogin(w, r, pending.LoginChallenge, pending.Email, []string{amrPassword, amrOTP, amrMFA})
+				return
+			}
+			if err != mfa.ErrInvalidCode {
+				log.Printf("Error verifying 2FA of %s: %v", pending.Email, err)
+				http.Error(w, "Internal error", http.StatusInternalServerError)
+				return
+			}
+		} else {
+			codes, err := h.mfa.ConfirmEnrollment(pending.Email, code)
+			if err == nil {
+				if err := h.mfa.VerifyChallenge(cookie.Value); err != nil {
+					log.Printf("Error updating 2FA challenge: %v", err)
+					http.Error(w, "Internal error", http.StatusInternalServerError)
+					return
+				}
+				renderMFAPage(w, mfaPage{State: "codes", Email: pending.Email, Codes: codes, FormAction: "/login/mfa"})
+				return
+			}
+			if err != mfa.ErrInvalidCode {
+				log.Printf("Error confirming 2FA of %s: %v", pending.Email, err)
+				http.Error(w, "Internal error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// Wrong code: counts against the challenge and the account lockout
+		if err := h.mfa.FailChallenge(cookie.Value); err != nil {
+			log.Printf("Error updating 2FA challenge: %v", err)
+		}
+		wait, err := h.lockouts.RecordFailure(attempt)
+		if err != nil {
+			log.Printf("Error recording failed login: %v", err)
+		}
+		if wait > 0 {
+			h.endSecondFactor(w, cookie.Value)
+			renderMFAPage(w, mfaPage{State: "expired", Error: lockedMessage(wait)})
+			return
+		}
+		page.Error = "Invalid code"
+	}
+
+	if !enabled {
+		enrollment, err := h.mfa.BeginEnrollment(pending.Email)
+		if err != nil {
+			log.Printf("Error starting 2FA enrollment for %s: %v", pending.Email, err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		page.State = "setup"
+		page.Required = true
+		page.Secret = groupSecret(enrollment.Secret)
+		page.URI = template.URL(enrollment.URI)
+	}
+	renderMFAPage(w, page)
+}
+
+// endSecondFactor forgets a pending login and clears its cookie
+func (h *LoginConsentHandler) endSecondFactor(w http.ResponseWriter, id string) {
+	if err := h.mfa.EndChallenge(id); err != nil {
+		log.Printf("Error ending 2FA challenge: %v", err)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     mfaCookie,
+		Value:    "",
+		Path:     "/login",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+}
+
+// groupSecret splits a base32 secret into blocks of four for typing
+func groupSecret(secret string) string {
+	var groups []string
+	for len(secret) > 4 {
+		groups = append(groups, secret[:4])
+		secret = secret[4:]
+	}
+	return strings.Join(append(groups, secret), " ")
+}
+
+// mfaPage is the data of the 2FA pages shared by /login/mfa and /account/mfa
+type mfaPage struct {
+	State      string // verify, setup, codes, status or expired
+	Email      string
+	Error      string
+	Notice     string
+	FormAction string
+	CSRF       string       // account page only
+	Secret     string       // setup
+	URI        template.URL // setup: otpauth:// link
+	Required   bool         // 2FA is mandatory for the user's role
+	Codes      []string     // codes: recovery codes, shown once
+	Status     *mfa.Status  // status
+}
+
+// renderMFAPage renders the 2FA pages
+func renderMFAPage(w http.ResponseWriter, page mfaPage) {
+	tmpl := `<!DOCTYPE html>
+<html>
+<head>
+    <title>Indian Store MCP - Two-factor authentication</title>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 440px; margin: 60px auto; padding: 20px; color: #333; }
+        .box { border: 1px solid #e0e0e0; border-radius: 10px; padding: 30px; }
+        h1 { font-size: 22px; margin: 0 0 10px 0; }
+        p { font-size: 14px; line-height: 1.5; }
+        .error { background: #fee; color: #c33; padding: 10px; border-radius: 5px; margin-bottom: 15px; font-size: 14px; }
+        .notice { background: #e8f5e9; color: #2e7d32; padding: 10px; border-radius: 5px; margin-bottom: 15px; font-size: 14px; }
+        .secret { font-family: monospace; font-size: 16px; background: #f5f5f5; padding: 10px; border-radius: 5px; text-align: center; letter-spacing: 1px; }
+        .codes { font-family: monospace; font-size: 15px; background: #f5f5f5; padding: 10px 10px 10px 30px; border-radius: 5px; columns: 2; }
+        input[type=text] { width: 100%; padding: 10px; border: 1px solid #ddd; border-radius: 5px; font-size: 18px; letter-spacing: 2px; box-sizing: border-box; margin-bottom: 12px; }
+        button { padding: 10px 18px; border-radius: 5px; border: none; font-size: 15px; cursor: pointer; background: #667eea; color: white; }
+        .secondary { background: #eee; color: #333; }
+        form + form { margin-top: 20px; }
+        a { color: #667eea; }
+    </style>
+</head>
+<body>
+    <div class="box">
+    {{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+    {{if .Notice}}<div class="notice">{{.Notice}}</div>{{end}}
+
+    {{if eq .State "verify"}}
+        <h1>Two-factor authentication</h1>
+        <p>Enter the 6-digit code from your authenticator app for <strong>{{.Email}}</strong>, or one of your recovery codes.</p>
+        <form method="POST" action="{{.FormAction}}">
+            <input type="text" name="code" autocomplete="one-time-code" inputmode="text" required autofocus>
+            <button type="submit">Verify</button>
+        </form>
+
+    {{else if eq .State "setup"}}
+        <h1>Set up two-factor authentication</h1>
+        {{if .Required}}<p>Your account requires two-factor authentication.</p>{{end}}
+        <p>Add this account to an authenticator app: <a href="{{.URI}}">open it in your authenticator</a>, or enter this key manually:</p>
+        <div class="secret">{{.Secret}}</div>
+        <p>Then enter the 6-digit code the app shows.</p>
+        <form method="POST" action="{{.FormAction}}">
+            {{if .CSRF}}<input type="hidden" name="csrf" value="{{.CSRF}}">{{end}}
+            <input type="hidden" name="action" value="enroll">
+            <input type="text" name="code" autocomplete="one-time-code" inputmode="numeric" required autofocus>
+            <button type="submit">Turn on</button>
+        </form>
+
+    {{else if eq .State "codes"}}
+        <h1>Save your recovery codes</h1>
+        <p>Each code signs you in once if you lose your authenticator. They are shown only now; keep them somewhere safe.</p>
+        <ol class="codes">{{range .Codes}}<li>{{.}}</li>{{end}}</ol>
+        {{if .CSRF}}
+        <p><a href="{{.FormAction}}">Done</a></p>
+        {{else}}
+        <form method="POST" action="{{.FormAction}}">
+            <button type="submit">Continue</button>
+        </form>
+        {{end}}
+
+    {{else if eq .State "status"}}
+        <h1>Two-factor authentication</h1>
+        <p>Two-factor authentication is <strong>on</strong> for {{.Email}}. You have {{.Status.RecoveryCodesLeft}} unused recovery codes.</p>
+        <form method="POST" action="{{.FormAction}}">
+            <input type="hidden" name="csrf" value="{{.CSRF}}">
+            <input type="hidden" name="action" value="regenerate">
+            <input type="text" name="code" placeholder="Current code" autocomplete="one-time-code" required>
+            <button type="submit" class="secondary">New recovery codes</button>
+        </form>
+        {{if not .Required}}
+        <form method="POST" action="{{.FormAction}}">
+            <input type="hidden" name="csrf" value="{{.CSRF}}">
+            <input type="hidden" name="action" value="disable">
+            <input type="text" name="code" placeholder="Current code" autocomplete="one-time-code" required>
+            <button type="submit" class="secondary">Turn off</button>
+        </form>
+        {{else}}
+        <p>Your account requires two-factor authentication, so it cannot be turned off.</p>
+        {{end}}
+
+    {{else}}
+        <h1>Sign-in expired</h1>
+        <p>Please start signing in again from the app you were using.</p>
+    {{end}}
+    </div>
+</body>
+</html>`
+
+	t := template.Must(template.New("mfa").Parse(tmpl))
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	t.Execute(w, page)
+}
@@ -3,11 +3,14 @@ package oauth
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"indian-store-mcp-server/internal/config"
 )
@@ -32,17 +35,34 @@ type UserInfo struct {
 }
 
 type IntrospectionResponse struct {
-	Active bool   `json:"active"`
-	Sub    string `json:"sub,omitempty"`
-	Email  string `json:"email,omitempty"`
-	Scope  string `json:"scope,omitempty"`
-	Exp    int64  `json:"exp,omitempty"`
+	Active   bool   `json:"active"`
+	Sub      string `json:"sub,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	Exp      int64  `json:"exp,omitempty"`
+}
+
+type JSONWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
 }
 
 func NewOryClient(cfg *config.Config) *OryClient {
 	return &OryClient{
 		config: cfg,
-		client: &http.Client{},
+		client: &http.Client{Timeout: 10 * time.Second},
 	}
 }
 
@@ -214,3 +234,337 @@ func (o *OryClient) RefreshToken(refreshToken string) (*TokenResponse, error) {
 
 	return &tokenResp, nil
 }
+
+// FetchJWKS downloads Hydra's public signing keys
+func (o *OryClient) FetchJWKS() (*JSONWebKeySet, error) {
+	// Use internal URL if available, otherwise fallback to external
+	jwksURL := fmt.Sprintf("%s/.well-known/jwks.json", o.config.OryURL)
+	if o.config.OryInternalURL != "" {
+		jwksURL = fmt.Sprintf("%s/.well-known/jwks.json", o.config.OryInternalURL)
+	}
+
+	resp, err := o.client.Get(jwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS fetch failed: %s - %s", resp.Status, string(body))
+	}
+
+	var keySet JSONWebKeySet
+	if err := json.Unmarshal(body, &keySet); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	return &keySet, nil
+}
+
+// FetchOpenIDConfiguration downloads Hydra's OpenID Connect discovery document
+func (o *OryClient) FetchOpenIDConfiguration() (map[string]interface{}, error) {
+	// Use internal URL if available, otherwise fallback to external
+	discoveryURL := fmt.Sprintf("%s/.well-known/openid-configuration", o.config.OryURL)
+	if o.config.OryInternalURL != "" {
+		discoveryURL = fmt.Sprintf("%s/.well-known/openid-configuration", o.config.OryInternalURL)
+	}
+
+	resp, err := o.client.Get(discoveryURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery fetch failed: %s - %s", resp.Status, string(body))
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse discovery document: %w", err)
+	}
+
+	return document, nil
+}
+
+// ErrClientNotFound is returned when Hydra has no client with the given ID
+var ErrClientNotFound = errors.New("oauth2 client not found")
+
+// adminBaseURL returns Hydra's admin API base URL
+func (o *OryClient) adminBaseURL() string {
+	if o.config.OryAdminURL == "" {
+		// Fallback if OryAdminURL not set
+		log.Println("WARNING: ORY_ADMIN_URL not configured, using default")
+		return "http://ory-hydra-admin.default.svc.cluster.local:4445"
+	}
+	return o.config.OryAdminURL
+}
+
+// adminAPIError is a non-2xx reply from Hydra's admin API
+type adminAPIError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *adminAPIError) Error() string {
+	return fmt.Sprintf("Hydra admin API returned %s - %s", e.Status, e.Body)
+}
+
+// adminRequest calls a Hydra admin API endpoint and decodes the JSON reply
+// into out, which may be nil
+func (o *OryClient) adminRequest(method, path string, query url.Values, body, out interface{}) error {
+	requestURL := o.adminBaseURL() + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reqBody = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequest(method, requestURL, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create admin request: %w", err)
+	}
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Hydra admin API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &adminAPIError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(respBody)}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse Hydra response: %w", err)
+	}
+	return nil
+}
+
+// adminClientRequest sends a request to Hydra's admin clients API and decodes
+// the client it returns (nil for 204 responses)
+func (o *OryClient) adminClientRequest(method, clientID string, client map[string]interface{}) (map[string]interface{}, error) {
+	path := "/admin/clients"
+	if clientID != "" {
+		path += "/" + url.PathEscape(clientID)
+	}
+
+	var body interface{}
+	if client != nil {
+		body = client
+	}
+	var result map[string]interface{}
+	err := o.adminRequest(method, path, nil, body, &result)
+
+	var apiErr *adminAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CreateClient registers a new OAuth2 client with Hydra
+func (o *OryClient) CreateClient(client map[string]interface{}) (map[string]interface{}, error) {
+	return o.adminClientRequest("POST", "", client)
+}
+
+// GetClient fetches an OAuth2 client from Hydra
+func (o *OryClient) GetClient(clientID string) (map[string]interface{}, error) {
+	return o.adminClientRequest("GET", clientID, nil)
+}
+
+// UpdateClient replaces an OAuth2 client in Hydra. Hydra keeps the existing
+// secret when client_secret is omitted.
+func (o *OryClient) UpdateClient(clientID string, client map[string]interface{}) (map[string]interface{}, error) {
+	return o.adminClientRequest("PUT", clientID, client)
+}
+
+// DeleteClient removes an OAuth2 client from Hydra
+func (o *OryClient) DeleteClient(clientID string) error {
+	_, err := o.adminClientRequest("DELETE", clientID, nil)
+	return err
+}
+
+// LogoutRequest is a pending logout from Hydra's logout flow
+type LogoutRequest struct {
+	Subject     string `json:"subject"`
+	SessionID   string `json:"sid"`
+	RPInitiated bool   `json:"rp_initiated"`
+	Client      *struct {
+		ClientID   string `json:"client_id"`
+		ClientName string `json:"client_name"`
+	} `json:"client"`
+}
+
+// GetLogoutRequest fetches the logout request behind a logout_challenge
+func (o *OryClient) GetLogoutRequest(challenge string) (*LogoutRequest, error) {
+	var logoutReq LogoutRequest
+	err := o.adminRequest("GET", "/admin/oauth2/auth/requests/logout",
+		url.Values{"logout_challenge": {challenge}}, nil, &logoutReq)
+	if err != nil {
+		return nil, err
+	}
+	return &logoutReq, nil
+}
+
+// AcceptLogoutRequest ends the user's Hydra session and returns where to
+// send the browser next
+func (o *OryClient) AcceptLogoutRequest(challenge string) (string, error) {
+	var result struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+	err := o.adminRequest("PUT", "/admin/oauth2/auth/requests/logout/accept",
+		url.Values{"logout_challenge": {challenge}}, nil, &result)
+	return result.RedirectTo, err
+}
+
+// RejectLogoutRequest cancels a logout; the user stays signed in
+func (o *OryClient) RejectLogoutRequest(challenge string) error {
+	return o.adminRequest("PUT", "/admin/oauth2/auth/requests/logout/reject",
+		url.Values{"logout_challenge": {challenge}}, nil, nil)
+}
+
+// RevokeToken forwards an RFC 7009 revocation request to Hydra, passing the
+// caller's client authentication through, and returns Hydra's reply
+func (o *OryClient) RevokeToken(form url.Values, authorization string) (int, []byte, error) {
+	// Use internal URL if available, otherwise fallback to external
+	revokeURL := fmt.Sprintf("%s/oauth2/revoke", o.config.OryURL)
+	if o.config.OryInternalURL != "" {
+		revokeURL = fmt.Sprintf("%s/oauth2/revoke", o.config.OryInternalURL)
+	}
+
+	req, err := http.NewRequest("POST", revokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create revocation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to revoke token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, body, nil
+}
+
+// ConsentRequest is a pending consent from Hydra's consent flow
+type ConsentRequest struct {
+	Skip              bool     `json:"skip"`
+	Subject           string   `json:"subject"`
+	RequestedScope    []string `json:"requested_scope"`
+	RequestedAudience []string `json:"requested_access_token_audience"`
+	RequestURL        string   `json:"request_url"`
+	Client            struct {
+		ClientID                string   `json:"client_id"`
+		ClientName              string   `json:"client_name"`
+		LogoURI                 string   `json:"logo_uri"`
+		ClientURI               string   `json:"client_uri"`
+		PolicyURI               string   `json:"policy_uri"`
+		TosURI                  string   `json:"tos_uri"`
+		RedirectURIs            []string `json:"redirect_uris"`
+		TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	} `json:"client"`
+}
+
+// GetConsentRequest fetches the consent request behind a consent_challenge
+func (o *OryClient) GetConsentRequest(challenge string) (*ConsentRequest, error) {
+	var consentReq ConsentRequest
+	err := o.adminRequest("GET", "/admin/oauth2/auth/requests/consent",
+		url.Values{"consent_challenge": {challenge}}, nil, &consentReq)
+	if err != nil {
+		return nil, err
+	}
+	return &consentReq, nil
+}
+
+// AcceptLoginRequest accepts a login and returns where to send the browser next
+func (o *OryClient) AcceptLoginRequest(challenge string, accept map[string]interface{}) (string, error) {
+	var result struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+	err := o.adminRequest("PUT", "/admin/oauth2/auth/requests/login/accept",
+		url.Values{"login_challenge": {challenge}}, accept, &result)
+	return result.RedirectTo, err
+}
+
+// AcceptConsentRequest grants consent and returns where to send the browser next
+func (o *OryClient) AcceptConsentRequest(challenge string, accept map[string]interface{}) (string, error) {
+	var result struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+	err := o.adminRequest("PUT", "/admin/oauth2/auth/requests/consent/accept",
+		url.Values{"consent_challenge": {challenge}}, accept, &result)
+	return result.RedirectTo, err
+}
+
+// RejectConsentRequest denies consent; the client receives errorCode
+func (o *OryClient) RejectConsentRequest(challenge, errorCode, description string) (string, error) {
+	var result struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+	err := o.adminRequest("PUT", "/admin/oauth2/auth/requests/consent/reject",
+		url.Values{"consent_challenge": {challenge}},
+		map[string]string{"error": errorCode, "error_description": description}, &result)
+	return result.RedirectTo, err
+}
+
+// ConsentSession is a consent a user granted to a client
+type ConsentSession struct {
+	GrantScope     []string  `json:"grant_scope"`
+	HandledAt      time.Time `json:"handled_at"`
+	ConsentRequest struct {
+		Client struct {
+			ClientID   string `json:"client_id"`
+			ClientName string `json:"client_name"`
+			LogoURI    string `json:"logo_uri"`
+		} `json:"client"`
+	} `json:"consent_request"`
+}
+
+// ListConsentSessions returns the consents a subject has granted
+func (o *OryClient) ListConsentSessions(subject string) ([]ConsentSession, error) {
+	var list []ConsentSession
+	err := o.adminRequest("GET", "/admin/oauth2/auth/sessions/consent",
+		url.Values{"subject": {subject}, "page_size": {"500"}}, nil, &list)
+	return list, err
+}
+
+// RevokeConsentSessions revokes a subject's consent to one client, or to
+// all clients when clientID is empty. Hydra also revokes the tokens issued
+// under it.
+func (o *OryClient) RevokeConsentSessions(subject, clientID string) error {
+	query := url.Values{"subject": {subject}}
+	if clientID == "" {
+		query.Set("all", "true")
+	} else {
+		query.Set("client", clientID)
+	}
+	return o.adminRequest("DELETE", "/admin/oauth2/auth/sessions/consent", query, nil, nil)
+}
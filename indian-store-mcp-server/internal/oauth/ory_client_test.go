@@ -0,0 +1,52 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"indian-store-mcp-server/internal/config"
+)
+
+func TestAdminClientRequestNotFound(t *testing.T) {
+	hydra := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/clients/gone" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		http.Error(w, `{"error":"Unable to locate the resource"}`, http.StatusNotFound)
+	}))
+	defer hydra.Close()
+
+	client := NewOryClient(&config.Config{OryAdminURL: hydra.URL})
+	if _, err := client.GetClient("gone"); err != ErrClientNotFound {
+		t.Errorf("GetClient() error = %v, want ErrClientNotFound", err)
+	}
+}
+
+func TestAcceptLoginRequest(t *testing.T) {
+	hydra := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/admin/oauth2/auth/requests/login/accept" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.URL.Query().Get("login_challenge"); got != "a&b" {
+			t.Errorf("login_challenge = %q, want it escaped intact", got)
+		}
+		var accept map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&accept)
+		if accept["subject"] != "user@example.com" {
+			t.Errorf("subject = %v", accept["subject"])
+		}
+		json.NewEncoder(w).Encode(map[string]string{"redirect_to": "https://auth.example.com/next"})
+	}))
+	defer hydra.Close()
+
+	client := NewOryClient(&config.Config{OryAdminURL: hydra.URL})
+	redirectTo, err := client.AcceptLoginRequest("a&b", map[string]interface{}{"subject": "user@example.com"})
+	if err != nil {
+		t.Fatalf("AcceptLoginRequest() error = %v", err)
+	}
+	if redirectTo != "https://auth.example.com/next" {
+		t.Errorf("redirectTo = %q", redirectTo)
+	}
+}
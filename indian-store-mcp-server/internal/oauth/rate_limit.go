@@ -0,0 +1,62 @@
+package oauth
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter counts requests per key in fixed windows. It is in-memory, so
+// each replica enforces its own limits.
+type rateLimiter struct {
+	limit     int // 0 disables the limiter
+	window    time.Duration
+	windows   map[string]*rateWindow
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Allow counts a request for key. When the limit is reached it returns
+// false and the time until the window resets.
+func (l *rateLimiter) Allow(key string) (bool, time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Drop finished windows so one-off callers do not pile up
+	if now.Sub(l.lastSweep) >= l.window {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= l.window {
+				delete(l.windows, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
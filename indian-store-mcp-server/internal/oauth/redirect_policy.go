@@ -0,0 +1,103 @@
+package oauth
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"indian-store-mcp-server/internal/config"
+)
+
+// RedirectPolicy decides which redirect URIs a dynamically registered client
+// may use. Without it anyone could register a client that sends
+// authorization codes to a domain they control.
+type RedirectPolicy struct {
+	allowedHosts  []string // exact hosts, "*.example.com" for subdomains, or "*" for any
+	allowLoopback bool
+}
+
+func NewRedirectPolicy(cfg *config.Config) *RedirectPolicy {
+	hosts := make([]string, 0, len(cfg.RedirectAllowedHosts))
+	for _, host := range cfg.RedirectAllowedHosts {
+		hosts = append(hosts, strings.ToLower(host))
+	}
+	return &RedirectPolicy{
+		allowedHosts:  hosts,
+		allowLoopback: cfg.RedirectAllowLoopback,
+	}
+}
+
+// Check validates every URI and reports the first that breaks a rule
+func (p *RedirectPolicy) Check(uris []string) *clientMetadataError {
+	for _, uri := range uris {
+		if rule := p.violation(uri); rule != "" {
+			return &clientMetadataError{
+				Code:        "invalid_redirect_uri",
+				Description: fmt.Sprintf("redirect_uri %q %s", uri, rule),
+			}
+		}
+	}
+	return nil
+}
+
+// violation returns the rule uri breaks, or "" if it is acceptable
+func (p *RedirectPolicy) violation(uri string) string {
+	if strings.Contains(uri, "#") {
+		return "must not contain a fragment"
+	}
+	if strings.Contains(uri, "*") {
+		return "must not contain wildcards"
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return "is not an absolute URI"
+	}
+	if u.User != nil {
+		return "must not contain user credentials"
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if isLoopbackHost(host) {
+		if !p.allowLoopback {
+			return "uses a loopback host, which is not allowed here"
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return "must use http or https"
+		}
+		return ""
+	}
+
+	if u.Scheme != "https" {
+		return "must use https (http is only allowed for loopback)"
+	}
+	if !p.hostAllowed(host) {
+		return fmt.Sprintf("has host %q, which is not in the allowed list", host)
+	}
+	return ""
+}
+
+func (p *RedirectPolicy) hostAllowed(host string) bool {
+	for _, pattern := range p.allowedHosts {
+		switch {
+		case pattern == "*":
+			return true
+		case strings.HasPrefix(pattern, "*."):
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+		case host == pattern:
+			return true
+		}
+	}
+	return false
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -1,46 +1,70 @@
 package oauth
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"indian-store-mcp-server/internal/config"
 )
 
 type RegistrationHandler struct {
-	config    *config.Config
-	oryClient *OryClient
+	config        *config.Config
+	oryClient     *OryClient
+	store         *RegistrationStore
+	initialTokens *InitialAccessTokenStore
+	redirects     *RedirectPolicy
+	ipLimiter     *rateLimiter
+	globalLimiter *rateLimiter
+	proxies       *TrustedProxies
 }
 
 type ClientRegistrationRequest struct {
-	ClientName    string   `json:"client_name,omitempty"`
-	RedirectURIs  []string `json:"redirect_uris"`
-	GrantTypes    []string `json:"grant_types,omitempty"`
-	ResponseTypes []string `json:"response_types,omitempty"`
-	Scope         string   `json:"scope,omitempty"`
-	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method,omitempty"`
+	ClientID                string   `json:"client_id,omitempty"` // only in RFC 7592 updates
+	ClientName              string   `json:"client_name,omitempty"`
+	LogoURI                 string   `json:"logo_uri,omitempty"`
+	RedirectURIs            []string `json:"redirect_uris"`
+	GrantTypes              []string `json:"grant_types,omitempty"`
+	ResponseTypes           []string `json:"response_types,omitempty"`
+	Scope                   string   `json:"scope,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
 }
 
 type ClientRegistrationResponse struct {
 	ClientID                string   `json:"client_id"`
 	ClientSecret            string   `json:"client_secret,omitempty"`
 	ClientName              string   `json:"client_name,omitempty"`
+	LogoURI                 string   `json:"logo_uri,omitempty"`
 	RedirectURIs            []string `json:"redirect_uris"`
 	GrantTypes              []string `json:"grant_types"`
 	ResponseTypes           []string `json:"response_types"`
 	Scope                   string   `json:"scope,omitempty"`
 	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
 	ClientSecretExpiresAt   int64    `json:"client_secret_expires_at"`
+	RegistrationAccessToken string   `json:"registration_access_token,omitempty"`
+	RegistrationClientURI   string   `json:"registration_client_uri,omitempty"`
 }
 
-func NewRegistrationHandler(cfg *config.Config, oryClient *OryClient) *RegistrationHandler {
+func NewRegistrationHandler(cfg *config.Config, oryClient *OryClient, store *RegistrationStore, initialTokens *InitialAccessTokenStore) *RegistrationHandler {
+	window := time.Duration(cfg.RegistrationRateLimitWindow) * time.Second
+	if cfg.RegistrationRequireInitialToken {
+		log.Println("Client registration requires an initial access token")
+	}
 	return &RegistrationHandler{
-		config:    cfg,
-		oryClient: oryClient,
+		config:        cfg,
+		oryClient:     oryClient,
+		store:         store,
+		initialTokens: initialTokens,
+		redirects:     NewRedirectPolicy(cfg),
+		ipLimiter:     newRateLimiter(cfg.RegistrationRateLimitPerIP, window),
+		globalLimiter: newRateLimiter(cfg.RegistrationRateLimitGlobal, window),
+		proxies:       NewTrustedProxies(cfg),
 	}
 }
 
@@ -51,6 +75,29 @@ func (h *RegistrationHandler) HandleRegister(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// Rate limits come first so they also slow down token guessing
+	ip := h.proxies.ClientIP(r)
+	if ok, retryAfter := h.ipLimiter.Allow(ip); !ok {
+		log.Printf("Registration rate limit exceeded for %s", ip)
+		rateLimited(w, retryAfter)
+		return
+	}
+	if ok, retryAfter := h.globalLimiter.Allow(""); !ok {
+		log.Printf("Global registration rate limit exceeded (request from %s)", ip)
+		rateLimited(w, retryAfter)
+		return
+	}
+
+	initialToken := ""
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		initialToken = strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	if h.config.RegistrationRequireInitialToken && initialToken == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		jsonError(w, "invalid_token", "An initial access token is required to register clients", http.StatusUnauthorized)
+		return
+	}
+
 	var req ClientRegistrationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Printf("Failed to decode registration request: %v", err)
@@ -58,102 +105,326 @@ func (h *RegistrationHandler) HandleRegister(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Validate request
-	if len(req.RedirectURIs) == 0 {
-		jsonError(w, "invalid_redirect_uri", "At least one redirect_uri is required", http.StatusBadRequest)
+	if metadataErr := h.prepareRegistration(&req); metadataErr != nil {
+		log.Printf("Rejected client registration (%s): %s", req.ClientName, metadataErr.Description)
+		jsonError(w, metadataErr.Code, metadataErr.Description, http.StatusBadRequest)
 		return
 	}
 
-	// Set defaults
-	if len(req.GrantTypes) == 0 {
-		req.GrantTypes = []string{"authorization_code", "refresh_token"}
+	metadataErr, err := h.checkNameCap(req.ClientName)
+	if err != nil {
+		log.Printf("Failed to count clients named %q: %v", req.ClientName, err)
+		jsonError(w, "server_error", "Failed to register client", http.StatusInternalServerError)
+		return
 	}
-	if len(req.ResponseTypes) == 0 {
-		req.ResponseTypes = []string{"code"}
+	if metadataErr != nil {
+		log.Printf("Rejected client registration (%s): %s", req.ClientName, metadataErr.Description)
+		jsonError(w, metadataErr.Code, metadataErr.Description, http.StatusBadRequest)
+		return
 	}
-	if req.Scope == "" {
-		req.Scope = "openid offline_access email profile"
+
+	// Spend one use of the initial access token
+	initialTokenID := 0
+	if h.config.RegistrationRequireInitialToken {
+		id, err := h.initialTokens.Consume(initialToken)
+		if err == errInvalidInitialAccessToken {
+			log.Printf("Rejected client registration (%s): invalid initial access token from %s", req.ClientName, ip)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			jsonError(w, "invalid_token", "Invalid, expired or used-up initial access token", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to check initial access token: %v", err)
+			jsonError(w, "server_error", "Failed to register client", http.StatusInternalServerError)
+			return
+		}
+		initialTokenID = id
 	}
-	if req.TokenEndpointAuthMethod == "" {
-		req.TokenEndpointAuthMethod = "client_secret_basic"
+	releaseInitialToken := func() {
+		if initialTokenID == 0 {
+			return
+		}
+		if err := h.initialTokens.Release(initialTokenID); err != nil {
+			log.Printf("Failed to release initial access token %d: %v", initialTokenID, err)
+		}
 	}
 
-	// Forward request to Ory Hydra admin API
-	oryRequest := map[string]interface{}{
-		"client_name":                req.ClientName,
-		"redirect_uris":              req.RedirectURIs,
-		"grant_types":                req.GrantTypes,
-		"response_types":             req.ResponseTypes,
-		"scope":                      req.Scope,
-		"token_endpoint_auth_method": req.TokenEndpointAuthMethod,
+	// Create the client through the Ory Hydra admin API
+	created, err := h.oryClient.CreateClient(hydraClient(&req))
+	if err != nil {
+		log.Printf("Failed to register client with Ory Hydra: %v", err)
+		releaseInitialToken()
+		jsonError(w, "server_error", "Failed to register client with OAuth provider", http.StatusInternalServerError)
+		return
 	}
+	clientID := getStringFromMap(created, "client_id")
 
-	jsonData, err := json.Marshal(oryRequest)
+	// Issue the registration access token for RFC 7592 management
+	token, err := h.store.Create(clientID, req.ClientName)
 	if err != nil {
-		log.Printf("Failed to marshal Ory request: %v", err)
-		jsonError(w, "server_error", "Internal server error", http.StatusInternalServerError)
+		log.Printf("Failed to store registration for %s, removing client: %v", clientID, err)
+		if err := h.oryClient.DeleteClient(clientID); err != nil {
+			log.Printf("Failed to remove client %s: %v", clientID, err)
+		}
+		releaseInitialToken()
+		jsonError(w, "server_error", "Failed to register client", http.StatusInternalServerError)
 		return
 	}
 
-	// Call Ory Hydra admin API to create client
-	adminURL := fmt.Sprintf("%s/admin/clients", h.config.OryAdminURL)
-	if adminURL == "/admin/clients" {
-		// Fallback if OryAdminURL not set
-		log.Println("WARNING: ORY_ADMIN_URL not configured, using default")
-		adminURL = "http://ory-hydra-admin.default.svc.cluster.local:4445/admin/clients"
+	// Build RFC 7591 compliant response
+	response := clientResponse(created)
+	response.ClientSecret = getStringFromMap(created, "client_secret")
+	response.RegistrationAccessToken = token
+	response.RegistrationClientURI = registrationClientURI(r, clientID)
+
+	log.Printf("Successfully registered client: %s (name: %s, auth method: %s)",
+		response.ClientID, response.ClientName, response.TokenEndpointAuthMethod)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleClientConfiguration implements the RFC 7592 client configuration
+// endpoint: GET, PUT and DELETE on /oauth/register/{client_id}, authorized
+// by the registration access token issued at creation
+func (h *RegistrationHandler) HandleClientConfiguration(w http.ResponseWriter, r *http.Request) {
+	clientID := strings.TrimPrefix(r.URL.Path, "/oauth/register/")
+	if clientID == "" || strings.Contains(clientID, "/") {
+		http.NotFound(w, r)
+		return
 	}
 
-	httpReq, err := http.NewRequest("POST", adminURL, bytes.NewBuffer(jsonData))
+	token := ""
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		token = strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	if token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		jsonError(w, "invalid_token", "Registration access token required", http.StatusUnauthorized)
+		return
+	}
+	if err := h.store.Verify(clientID, token); err != nil {
+		if err != errInvalidRegistrationToken {
+			log.Printf("Failed to verify registration token for %s: %v", clientID, err)
+			jsonError(w, "server_error", "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Invalid registration access token for client %s", clientID)
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		jsonError(w, "invalid_token", "Invalid registration access token", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.readClient(w, r, clientID)
+	case http.MethodPut:
+		h.updateClient(w, r, clientID)
+	case http.MethodDelete:
+		h.deleteClient(w, clientID)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *RegistrationHandler) readClient(w http.ResponseWriter, r *http.Request, clientID string) {
+	client, err := h.oryClient.GetClient(clientID)
+	if err == ErrClientNotFound {
+		// Deleted in Hydra behind our back; the token is no longer good
+		h.store.Delete(clientID)
+		jsonError(w, "invalid_token", "Invalid registration access token", http.StatusUnauthorized)
+		return
+	}
 	if err != nil {
-		log.Printf("Failed to create Ory request: %v", err)
-		jsonError(w, "server_error", "Internal server error", http.StatusInternalServerError)
+		log.Printf("Failed to fetch client %s: %v", clientID, err)
+		jsonError(w, "server_error", "Failed to read client", http.StatusInternalServerError)
 		return
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
+	response := clientResponse(client)
+	response.RegistrationClientURI = registrationClientURI(r, clientID)
+	writeClientResponse(w, response)
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+func (h *RegistrationHandler) updateClient(w http.ResponseWriter, r *http.Request, clientID string) {
+	var req ClientRegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid_request", "Invalid JSON in request body", http.StatusBadRequest)
+		return
+	}
+	if req.ClientID != clientID {
+		jsonError(w, "invalid_request", "client_id in the body must match the request URL", http.StatusBadRequest)
+		return
+	}
+
+	// Same rules as creation
+	if metadataErr := h.prepareRegistration(&req); metadataErr != nil {
+		log.Printf("Rejected update of client %s: %s", clientID, metadataErr.Description)
+		jsonError(w, metadataErr.Code, metadataErr.Description, http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.oryClient.GetClient(clientID)
+	if err == ErrClientNotFound {
+		// Deleted in Hydra behind our back; the token is no longer good
+		h.store.Delete(clientID)
+		jsonError(w, "invalid_token", "Invalid registration access token", http.StatusUnauthorized)
+		return
+	}
 	if err != nil {
-		log.Printf("Failed to call Ory Hydra: %v", err)
-		jsonError(w, "server_error", "Failed to register client with OAuth provider", http.StatusInternalServerError)
+		log.Printf("Failed to fetch client %s: %v", clientID, err)
+		jsonError(w, "server_error", "Failed to update client", http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	// Switching between public and confidential would need a new secret
+	currentPublic := getStringFromMap(current, "token_endpoint_auth_method") == AuthMethodNone
+	if currentPublic != (req.TokenEndpointAuthMethod == AuthMethodNone) {
+		jsonError(w, "invalid_client_metadata", "A client cannot switch between public and confidential", http.StatusBadRequest)
+		return
+	}
 
-	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		log.Printf("Ory Hydra returned error: %d - %s", resp.StatusCode, string(body))
-		jsonError(w, "server_error", "Failed to register client", http.StatusInternalServerError)
+	// A renamed client counts against the cap of its new name
+	if req.ClientName != getStringFromMap(current, "client_name") {
+		metadataErr, err := h.checkNameCap(req.ClientName)
+		if err != nil {
+			log.Printf("Failed to count clients named %q: %v", req.ClientName, err)
+			jsonError(w, "server_error", "Failed to update client", http.StatusInternalServerError)
+			return
+		}
+		if metadataErr != nil {
+			log.Printf("Rejected update of client %s: %s", clientID, metadataErr.Description)
+			jsonError(w, metadataErr.Code, metadataErr.Description, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Hydra's PUT replaces the whole client, so only the RFC 7591 metadata is
+	// overwritten and everything else (audience, lifespans, ...) is kept
+	client := current
+	for key, value := range hydraClient(&req) {
+		client[key] = value
+	}
+	client["client_id"] = clientID
+	updated, err := h.oryClient.UpdateClient(clientID, client)
+	if err != nil {
+		log.Printf("Failed to update client %s: %v", clientID, err)
+		jsonError(w, "server_error", "Failed to update client", http.StatusInternalServerError)
 		return
 	}
+	if err := h.store.UpdateName(clientID, req.ClientName); err != nil {
+		log.Printf("Failed to record new name of client %s: %v", clientID, err)
+	}
+
+	log.Printf("Client %s updated its registration", clientID)
 
-	// Parse Ory response
-	var oryResponse map[string]interface{}
-	if err := json.Unmarshal(body, &oryResponse); err != nil {
-		log.Printf("Failed to parse Ory response: %v", err)
-		jsonError(w, "server_error", "Internal server error", http.StatusInternalServerError)
+	response := clientResponse(updated)
+	response.RegistrationClientURI = registrationClientURI(r, clientID)
+	writeClientResponse(w, response)
+}
+
+func (h *RegistrationHandler) deleteClient(w http.ResponseWriter, clientID string) {
+	if err := h.oryClient.DeleteClient(clientID); err != nil && err != ErrClientNotFound {
+		log.Printf("Failed to delete client %s: %v", clientID, err)
+		jsonError(w, "server_error", "Failed to delete client", http.StatusInternalServerError)
 		return
 	}
+	if err := h.store.Delete(clientID); err != nil {
+		log.Printf("Failed to delete registration of client %s: %v", clientID, err)
+	}
 
-	// Build RFC 7591 compliant response
-	response := ClientRegistrationResponse{
-		ClientID:                getStringFromMap(oryResponse, "client_id"),
-		ClientSecret:            getStringFromMap(oryResponse, "client_secret"),
-		ClientName:              req.ClientName,
-		RedirectURIs:            req.RedirectURIs,
-		GrantTypes:              req.GrantTypes,
-		ResponseTypes:           req.ResponseTypes,
-		Scope:                   req.Scope,
-		TokenEndpointAuthMethod: req.TokenEndpointAuthMethod,
+	log.Printf("Client %s deleted its registration", clientID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// prepareRegistration applies defaults and validates client metadata; it is
+// shared by creation and RFC 7592 updates
+func (h *RegistrationHandler) prepareRegistration(req *ClientRegistrationRequest) *clientMetadataError {
+	// Validate request
+	if len(req.RedirectURIs) == 0 {
+		return &clientMetadataError{Code: "invalid_redirect_uri", Description: "At least one redirect_uri is required"}
+	}
+	if redirectErr := h.redirects.Check(req.RedirectURIs); redirectErr != nil {
+		return redirectErr
+	}
+
+	// Set defaults
+	if len(req.GrantTypes) == 0 {
+		req.GrantTypes = []string{"authorization_code", "refresh_token"}
+	}
+	if len(req.ResponseTypes) == 0 {
+		req.ResponseTypes = []string{"code"}
+	}
+	if req.Scope == "" {
+		req.Scope = DefaultClientScope
+	}
+	if req.TokenEndpointAuthMethod == "" {
+		req.TokenEndpointAuthMethod = AuthMethodSecretBasic
+	}
+
+	// Reject unsupported or insecure grant/auth combinations
+	return validateClientMetadata(req)
+}
+
+// checkNameCap caps look-alike clients, e.g. one connector registering on
+// every reconnect. Concurrent registrations can overshoot the cap slightly.
+func (h *RegistrationHandler) checkNameCap(name string) (*clientMetadataError, error) {
+	maxClients := h.config.RegistrationMaxClientsPerName
+	if maxClients <= 0 {
+		return nil, nil
+	}
+	count, err := h.store.CountByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxClients {
+		return &clientMetadataError{
+			Code:        "invalid_client_metadata",
+			Description: fmt.Sprintf("Too many clients are registered with client_name %q", name),
+		}, nil
+	}
+	return nil, nil
+}
+
+// hydraClient is the Hydra admin API representation of a registration
+func hydraClient(req *ClientRegistrationRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"client_name":                req.ClientName,
+		"logo_uri":                   req.LogoURI,
+		"redirect_uris":              req.RedirectURIs,
+		"grant_types":                req.GrantTypes,
+		"response_types":             req.ResponseTypes,
+		"scope":                      req.Scope,
+		"token_endpoint_auth_method": req.TokenEndpointAuthMethod,
+	}
+}
+
+// clientResponse converts a Hydra client to RFC 7591 client metadata
+func clientResponse(client map[string]interface{}) ClientRegistrationResponse {
+	return ClientRegistrationResponse{
+		ClientID:                getStringFromMap(client, "client_id"),
+		ClientName:              getStringFromMap(client, "client_name"),
+		LogoURI:                 getStringFromMap(client, "logo_uri"),
+		RedirectURIs:            getStringsFromMap(client, "redirect_uris"),
+		GrantTypes:              getStringsFromMap(client, "grant_types"),
+		ResponseTypes:           getStringsFromMap(client, "response_types"),
+		Scope:                   getStringFromMap(client, "scope"),
+		TokenEndpointAuthMethod: getStringFromMap(client, "token_endpoint_auth_method"),
 		ClientSecretExpiresAt:   0, // 0 means it doesn't expire
 	}
+}
 
-	log.Printf("Successfully registered client: %s (name: %s)", response.ClientID, response.ClientName)
+func registrationClientURI(r *http.Request, clientID string) string {
+	return BaseURL(r) + "/oauth/register/" + url.PathEscape(clientID)
+}
 
+func writeClientResponse(w http.ResponseWriter, response ClientRegistrationResponse) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -164,6 +435,23 @@ func getStringFromMap(m map[string]interface{}, key string) string {
 	return ""
 }
 
+func getStringsFromMap(m map[string]interface{}, key string) []string {
+	list, _ := m[key].([]interface{})
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// rateLimited answers a request over a registration rate limit
+func rateLimited(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	jsonError(w, "too_many_requests", "Too many client registrations, try again later", http.StatusTooManyRequests)
+}
+
 func jsonError(w http.ResponseWriter, errorCode, errorDesc string, status int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,101 @@
+package oauth
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for tokens issued without explicit limits
+const (
+	defaultInitialTokenUses = 1
+	defaultInitialTokenTTL  = 7 * 24 * time.Hour
+)
+
+type issueInitialTokenRequest struct {
+	Description string `json:"description"`
+	MaxUses     *int   `json:"max_uses,omitempty"`   // 0 for unlimited
+	ExpiresIn   *int   `json:"expires_in,omitempty"` // seconds, 0 for never
+}
+
+// HandleInitialAccessTokens lists (GET) and issues (POST) initial access
+// tokens on /admin/registration-tokens
+func (h *RegistrationHandler) HandleInitialAccessTokens(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		tokens, err := h.initialTokens.List()
+		if err != nil {
+			log.Printf("Failed to list initial access tokens: %v", err)
+			jsonError(w, "server_error", "Failed to list tokens", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"tokens": tokens})
+
+	case http.MethodPost:
+		var req issueInitialTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "invalid_request", "Invalid JSON in request body", http.StatusBadRequest)
+			return
+		}
+		maxUses, ttl := defaultInitialTokenUses, defaultInitialTokenTTL
+		if req.MaxUses != nil {
+			maxUses = *req.MaxUses
+		}
+		if req.ExpiresIn != nil {
+			ttl = time.Duration(*req.ExpiresIn) * time.Second
+		}
+		if maxUses < 0 || ttl < 0 {
+			jsonError(w, "invalid_request", "max_uses and expires_in must not be negative", http.StatusBadRequest)
+			return
+		}
+
+		token, err := h.initialTokens.Issue(req.Description, maxUses, ttl)
+		if err != nil {
+			log.Printf("Failed to issue initial access token: %v", err)
+			jsonError(w, "server_error", "Failed to issue token", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Issued initial access token %d (%s, max uses %d)", token.ID, token.Description, token.MaxUses)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(token)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleInitialAccessToken revokes a token: DELETE /admin/registration-tokens/{id}
+func (h *RegistrationHandler) HandleInitialAccessToken(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/admin/registration-tokens/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	found, err := h.initialTokens.Revoke(id)
+	if err != nil {
+		log.Printf("Failed to revoke initial access token %d: %v", id, err)
+		jsonError(w, "server_error", "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	log.Printf("Revoked initial access token %d", id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,175 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// errInvalidRegistrationToken covers both an unknown client and a wrong
+// token, so callers cannot probe which client IDs exist
+var errInvalidRegistrationToken = errors.New("invalid registration access token")
+
+// RegistrationStore keeps the registration access tokens (RFC 7592) of
+// dynamically registered clients and when each last got a token. Only a
+// SHA-256 of each registration access token is stored.
+type RegistrationStore struct {
+	db *sql.DB
+}
+
+// ClientRegistration is a dynamically registered client
+type ClientRegistration struct {
+	ClientID    string     `json:"client_id"`
+	ClientName  string     `json:"client_name"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastTokenAt *time.Time `json:"last_token_at,omitempty"` // nil if no token was ever issued
+}
+
+// NewRegistrationStore creates the store on the shared database connection
+func NewRegistrationStore(db *sql.DB) (*RegistrationStore, error) {
+	store := &RegistrationStore{db: db}
+
+	// Create table if not exists
+	if err := store.createTable(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// createTable creates the oauth_client_registrations table
+func (s *RegistrationStore) createTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS oauth_client_registrations (
+		client_id VARCHAR(255) PRIMARY KEY,
+		client_name VARCHAR(255) NOT NULL DEFAULT '',
+		token_hash CHAR(64) NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`
+
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+
+	// last_token_at was added later. Registrations that existed before are
+	// stamped with the migration time so they get a full idle window
+	// instead of being reaped on the first run.
+	var tracked bool
+	err := s.db.QueryRow(`
+	SELECT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_name = 'oauth_client_registrations' AND column_name = 'last_token_at'
+	)`).Scan(&tracked)
+	if err != nil || tracked {
+		return err
+	}
+	if _, err := s.db.Exec(`ALTER TABLE oauth_client_registrations ADD COLUMN IF NOT EXISTS last_token_at TIMESTAMP`); err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`UPDATE oauth_client_registrations SET last_token_at = NOW() WHERE last_token_at IS NULL`)
+	return err
+}
+
+// Create issues a registration access token for a new client
+func (s *RegistrationStore) Create(clientID, clientName string) (string, error) {
+	token, err := newRegistrationToken()
+	if err != nil {
+		return "", err
+	}
+
+	query := `INSERT INTO oauth_client_registrations (client_id, client_name, token_hash) VALUES ($1, $2, $3)`
+	if _, err := s.db.Exec(query, clientID, clientName, hashRegistrationToken(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Verify checks a registration access token against a client
+func (s *RegistrationStore) Verify(clientID, token string) error {
+	var stored string
+	err := s.db.QueryRow(`SELECT token_hash FROM oauth_client_registrations WHERE client_id = $1`, clientID).Scan(&stored)
+	if err == sql.ErrNoRows {
+		return errInvalidRegistrationToken
+	}
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(hashRegistrationToken(token))) != 1 {
+		return errInvalidRegistrationToken
+	}
+	return nil
+}
+
+// UpdateName records a client_name change made through the configuration endpoint
+func (s *RegistrationStore) UpdateName(clientID, clientName string) error {
+	_, err := s.db.Exec(`UPDATE oauth_client_registrations SET client_name = $2 WHERE client_id = $1`, clientID, clientName)
+	return err
+}
+
+// CountByName returns how many registered clients share a client_name
+func (s *RegistrationStore) CountByName(clientName string) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM oauth_client_registrations WHERE client_name = $1`, clientName).Scan(&count)
+	return count, err
+}
+
+// RecordTokenIssued notes that Hydra just issued a token to a client.
+// Clients we did not register are ignored.
+func (s *RegistrationStore) RecordTokenIssued(clientID string) error {
+	_, err := s.db.Exec(`UPDATE oauth_client_registrations SET last_token_at = NOW() WHERE client_id = $1`, clientID)
+	return err
+}
+
+// Idle returns up to limit registrations that have not been issued a token
+// (or, if never used, were created) more than maxIdle ago, oldest first
+func (s *RegistrationStore) Idle(maxIdle time.Duration, limit int) ([]ClientRegistration, error) {
+	rows, err := s.db.Query(`
+	SELECT client_id, client_name, created_at, last_token_at
+	FROM oauth_client_registrations
+	WHERE COALESCE(last_token_at, created_at) < NOW() - $1::integer * INTERVAL '1 second'
+	ORDER BY COALESCE(last_token_at, created_at)
+	LIMIT $2`, int(maxIdle.Seconds()), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	registrations := []ClientRegistration{}
+	for rows.Next() {
+		var reg ClientRegistration
+		var lastTokenAt sql.NullTime
+		if err := rows.Scan(&reg.ClientID, &reg.ClientName, &reg.CreatedAt, &lastTokenAt); err != nil {
+			return nil, err
+		}
+		if lastTokenAt.Valid {
+			reg.LastTokenAt = &lastTokenAt.Time
+		}
+		registrations = append(registrations, reg)
+	}
+	return registrations, rows.Err()
+}
+
+// Delete forgets a client's registration
+func (s *RegistrationStore) Delete(clientID string) error {
+	_, err := s.db.Exec(`DELETE FROM oauth_client_registrations WHERE client_id = $1`, clientID)
+	return err
+}
+
+func newRegistrationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashRegistrationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
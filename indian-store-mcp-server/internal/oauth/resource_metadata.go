@@ -0,0 +1,99 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Protected resource metadata (RFC 9728) and bearer challenges (RFC 6750)
+// let MCP clients discover how to get a token for /mcp on their own.
+
+// MCPResourcePath is the protected resource served by this server
+const MCPResourcePath = "/mcp"
+
+// Bearer token error codes from RFC 6750 section 3.1
+const (
+	ErrInvalidRequest    = "invalid_request"
+	ErrInvalidToken      = "invalid_token"
+	ErrInsufficientScope = "insufficient_scope"
+)
+
+// BaseURL reconstructs the public URL of this server, honouring the
+// X-Forwarded-* headers set by the gateway
+func BaseURL(r *http.Request) string {
+	scheme := "https"
+	if r.TLS == nil && r.Header.Get("X-Forwarded-Proto") == "" {
+		scheme = "http"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+
+	host := r.Host
+	if forwardedHost := r.Header.Get("X-Forwarded-Host"); forwardedHost != "" {
+		host = forwardedHost
+	}
+
+	return scheme + "://" + host
+}
+
+// ResourceMetadataURL is where clients fetch the metadata for /mcp
+func ResourceMetadataURL(r *http.Request) string {
+	return BaseURL(r) + "/.well-known/oauth-protected-resource" + MCPResourcePath
+}
+
+// HandleProtectedResourceMetadata serves /.well-known/oauth-protected-resource
+// and its /mcp path-suffixed form. Tokens for /mcp are issued by Hydra.
+func (h *DiscoveryHandler) HandleProtectedResourceMetadata(w http.ResponseWriter, r *http.Request) {
+	metadata := map[string]interface{}{
+		"resource":                 BaseURL(r) + MCPResourcePath,
+		"authorization_servers":    []string{h.Issuer(r)},
+		"scopes_supported":         SupportedScopes,
+		"bearer_methods_supported": []string{"header"},
+		"resource_name":            "Indian Store MCP Server",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(metadata)
+}
+
+// BearerChallenge builds a WWW-Authenticate value pointing at the resource
+// metadata. errorCode may be empty when the request carried no token at all;
+// scope lists what insufficient_scope requires.
+func BearerChallenge(r *http.Request, errorCode, errorDesc string, scope []string) string {
+	params := []string{authParam("resource_metadata", ResourceMetadataURL(r))}
+	if errorCode != "" {
+		params = append(params, authParam("error", errorCode))
+	}
+	if errorDesc != "" {
+		params = append(params, authParam("error_description", errorDesc))
+	}
+	if len(scope) > 0 {
+		params = append(params, authParam("scope", strings.Join(scope, " ")))
+	}
+	return "Bearer " + strings.Join(params, ", ")
+}
+
+var quotedStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func authParam(name, value string) string {
+	return fmt.Sprintf(`%s="%s"`, name, quotedStringEscaper.Replace(value))
+}
+
+// WriteBearerError rejects a request to a protected resource with a
+// WWW-Authenticate challenge and a JSON error body
+func WriteBearerError(w http.ResponseWriter, r *http.Request, status int, errorCode, errorDesc string) {
+	// RFC 6750: a request with no credentials gets a bare challenge; the
+	// JSON body still needs an error code
+	if errorCode == "" {
+		w.Header().Set("WWW-Authenticate", BearerChallenge(r, "", "", nil))
+		errorCode = "unauthorized"
+	} else {
+		w.Header().Set("WWW-Authenticate", BearerChallenge(r, errorCode, errorDesc, nil))
+	}
+	jsonError(w, errorCode, errorDesc, status)
+}
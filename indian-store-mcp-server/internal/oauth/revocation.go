@@ -0,0 +1,60 @@
+package oauth
+
+import (
+	"log"
+	"net/http"
+)
+
+// RevocationHandler serves /oauth/revoke (RFC 7009). Requests are proxied
+// to Hydra, which authenticates the client and revokes the token; a
+// successful revocation also evicts the token from the introspection cache
+// and puts JWT access tokens on the revocation list.
+type RevocationHandler struct {
+	oryClient *OryClient
+	validator *TokenValidator
+}
+
+func NewRevocationHandler(oryClient *OryClient, validator *TokenValidator) *RevocationHandler {
+	return &RevocationHandler{
+		oryClient: oryClient,
+		validator: validator,
+	}
+}
+
+// HandleRevoke proxies a token revocation request to Hydra
+func (h *RevocationHandler) HandleRevoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		jsonError(w, "invalid_request", "Invalid form body", http.StatusBadRequest)
+		return
+	}
+	token := r.PostForm.Get("token")
+	if token == "" {
+		jsonError(w, "invalid_request", "token is required", http.StatusBadRequest)
+		return
+	}
+
+	status, body, err := h.oryClient.RevokeToken(r.PostForm, r.Header.Get("Authorization"))
+	if err != nil {
+		log.Printf("Token revocation failed: %v", err)
+		jsonError(w, "server_error", "Failed to revoke token", http.StatusBadGateway)
+		return
+	}
+
+	if status == http.StatusOK {
+		h.validator.Forget(token)
+		log.Printf("Token revoked (hint: %s)", r.PostForm.Get("token_type_hint"))
+	} else {
+		log.Printf("Hydra rejected token revocation: %d %s", status, string(body))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(body)
+}
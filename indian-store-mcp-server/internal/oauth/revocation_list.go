@@ -0,0 +1,83 @@
+package oauth
+
+import (
+	"sync"
+	"time"
+)
+
+// revokedGrantRetention is how long a revoked grant is remembered. It must
+// be longer than any access token Hydra issues (ttl.access_token).
+const revokedGrantRetention = 24 * time.Hour
+
+// revocationList remembers JWT access tokens revoked through this server.
+// JWTs are verified locally without asking Hydra, so without it a revoked
+// token would keep working until exp. Tokens are listed by jti until they
+// expire; grants (a user's consent to a client, or to every client) by the
+// time they were revoked, rejecting tokens issued at or before it.
+//
+// The list is held in memory: revocations made before a restart, or
+// through another replica, still last until the token's exp.
+type revocationList struct {
+	tokens map[string]time.Time // jti -> exp
+	grants map[string]time.Time // grantKey -> revoked at
+	mu     sync.Mutex
+}
+
+func newRevocationList() *revocationList {
+	return &revocationList{
+		tokens: make(map[string]time.Time),
+		grants: make(map[string]time.Time),
+	}
+}
+
+// grantKey names a user's grant to clientID; an empty clientID is every client
+func grantKey(subject, clientID string) string {
+	return subject + "\x00" + clientID
+}
+
+// revokeToken lists the token with the given jti until it expires
+func (l *revocationList) revokeToken(jti string, exp time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(time.Now())
+	l.tokens[jti] = exp
+}
+
+// revokeGrant rejects the user's tokens for clientID (every client when
+// empty) issued up to now
+func (l *revocationList) revokeGrant(subject, clientID string) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+	l.grants[grantKey(subject, clientID)] = now
+}
+
+// revoked reports whether a token was revoked by jti or through its grant
+func (l *revocationList) revoked(jti, subject, clientID string, issuedAt int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.tokens[jti]; ok && jti != "" {
+		return true
+	}
+	for _, key := range []string{grantKey(subject, clientID), grantKey(subject, "")} {
+		if at, ok := l.grants[key]; ok && issuedAt <= at.Unix() {
+			return true
+		}
+	}
+	return false
+}
+
+// prune drops entries that can no longer match a valid token; callers hold mu
+func (l *revocationList) prune(now time.Time) {
+	for jti, exp := range l.tokens {
+		if now.After(exp.Add(clockSkew)) {
+			delete(l.tokens, jti)
+		}
+	}
+	for key, at := range l.grants {
+		if now.Sub(at) > revokedGrantRetention {
+			delete(l.grants, key)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package oauth
+
+import "strings"
+
+// Scopes understood by the MCP server. Tools declare which of these they need.
+const (
+	ScopeStoresRead = "stores:read"
+)
+
+// SupportedScopes is advertised in discovery as scopes_supported
+var SupportedScopes = []string{"openid", "offline_access", "email", "profile", ScopeStoresRead}
+
+// DefaultClientScope is granted to dynamically registered clients that do
+// not ask for anything specific
+const DefaultClientScope = "openid offline_access email profile " + ScopeStoresRead
+
+// scopeDescriptions are shown to users on the consent page
+var scopeDescriptions = map[string]string{
+	"openid":         "Confirm who you are",
+	"offline":        "Stay connected when you are not using the app",
+	"offline_access": "Stay connected when you are not using the app",
+	"email":          "See your email address",
+	"profile":        "See your name",
+	ScopeStoresRead:  "Search and read the Indian store catalog",
+}
+
+// requiredConsentScopes cannot be unchecked on the consent page
+var requiredConsentScopes = map[string]bool{
+	"openid": true,
+}
+
+// ScopeDescription returns the human description of a scope
+func ScopeDescription(scope string) string {
+	if description, ok := scopeDescriptions[scope]; ok {
+		return description
+	}
+	return "Use the " + scope + " permission"
+}
+
+// ParseScope splits a space-delimited OAuth scope string
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
@@ -0,0 +1,97 @@
+package oauth
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"indian-store-mcp-server/internal/config"
+)
+
+// TrustedProxies decides whose X-Forwarded-For header is believed. Anyone
+// who reaches the pod directly (NodePort, port-forward, another pod) could
+// otherwise claim any address and dodge the per-IP limits and lockouts.
+type TrustedProxies struct {
+	nets []*net.IPNet
+}
+
+// NewTrustedProxies builds the policy from TRUSTED_PROXIES. Entries are
+// checked by config.Load, so invalid ones cannot reach here.
+func NewTrustedProxies(cfg *config.Config) *TrustedProxies {
+	p := &TrustedProxies{}
+	for _, entry := range cfg.TrustedProxies {
+		if ipNet := parseIPOrCIDR(entry); ipNet != nil {
+			p.nets = append(p.nets, ipNet)
+		}
+	}
+	return p
+}
+
+// parseIPOrCIDR reads "10.0.0.0/8" or a single address as a network
+func parseIPOrCIDR(entry string) *net.IPNet {
+	if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+		return ipNet
+	}
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil
+	}
+	bits := 128
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+}
+
+// Trusted reports whether addr is one of the trusted proxies
+func (p *TrustedProxies) Trusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range p.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the caller's address. X-Forwarded-For is only read when
+// the connection comes from a trusted proxy; it is then walked from the
+// right, skipping trusted hops, and the first other address is the client.
+// Entries left of that were written by the client and are ignored.
+func (p *TrustedProxies) ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !p.Trusted(ip) {
+		return ip
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !p.Trusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// LockoutIP is the address failed logins are counted against. It is empty
+// when ClientIP could only get as far as a trusted proxy, so a gateway that
+// forwards without X-Forwarded-For cannot get itself, and everyone behind
+// it, locked out.
+func (p *TrustedProxies) LockoutIP(r *http.Request) string {
+	ip := p.ClientIP(r)
+	if p.Trusted(ip) {
+		return ""
+	}
+	return ip
+}
@@ -0,0 +1,78 @@
+package oauth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"indian-store-mcp-server/internal/config"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies := NewTrustedProxies(&config.Config{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.5"}})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct caller", "203.0.113.7:5000", "", "203.0.113.7"},
+		{"direct caller spoofing header", "203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:5000", "198.51.100.1", "198.51.100.1"},
+		{"trusted single address", "192.168.1.5:5000", "198.51.100.1", "198.51.100.1"},
+		{"client-written entries ignored", "10.1.2.3:5000", "1.2.3.4, 198.51.100.1", "198.51.100.1"},
+		{"chain of trusted proxies", "10.1.2.3:5000", "198.51.100.1, 10.9.9.9", "198.51.100.1"},
+		{"trusted proxy without header", "10.1.2.3:5000", "", "10.1.2.3"},
+		{"garbage entry", "10.1.2.3:5000", "198.51.100.1, not-an-ip", "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := proxies.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPTrustsNobodyByDefault(t *testing.T) {
+	proxies := NewTrustedProxies(&config.Config{})
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.1.2.3:5000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if got := proxies.ClientIP(r); got != "10.1.2.3" {
+		t.Errorf("ClientIP() = %q, want the connection address", got)
+	}
+}
+
+func TestLockoutIP(t *testing.T) {
+	proxies := NewTrustedProxies(&config.Config{TrustedProxies: []string{"10.0.0.0/8"}})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"forwarded client", "10.1.2.3:5000", "198.51.100.1", "198.51.100.1"},
+		{"spoofed header from outside", "203.0.113.7:5000", "10.1.2.3", "203.0.113.7"},
+		{"proxy without header", "10.1.2.3:5000", "", ""},
+		{"client claims a proxy address", "10.1.2.3:5000", "10.9.9.9", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/login", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := proxies.LockoutIP(r); got != tt.want {
+				t.Errorf("LockoutIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
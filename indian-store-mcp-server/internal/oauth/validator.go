@@ -0,0 +1,209 @@
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"indian-store-mcp-server/internal/config"
+)
+
+// Token validation strategies (config TOKEN_VALIDATION)
+const (
+	ValidationIntrospection = "introspection" // every token is introspected by Hydra
+	ValidationJWT           = "jwt"           // only JWT access tokens, verified locally
+	ValidationAuto          = "auto"          // JWTs locally, opaque tokens via introspection
+)
+
+// clockSkew is tolerated when checking exp and nbf
+const clockSkew = 30 * time.Second
+
+// TokenValidator checks access tokens presented to the MCP endpoint
+type TokenValidator struct {
+	mode      string
+	issuer    string
+	audience  string
+	oryClient *OryClient
+	keys      *jwksCache
+	cache     *IntrospectionCache // nil when INTROSPECTION_CACHE_TTL is 0
+	revoked   *revocationList
+}
+
+func NewTokenValidator(cfg *config.Config, oryClient *OryClient) *TokenValidator {
+	log.Printf("Access tokens validated by: %s", cfg.TokenValidation)
+	v := &TokenValidator{
+		mode:      cfg.TokenValidation,
+		issuer:    cfg.TokenIssuer,
+		audience:  cfg.TokenAudience,
+		oryClient: oryClient,
+		keys:      newJWKSCache(oryClient, time.Duration(cfg.JWKSCacheTTL)*time.Second),
+		revoked:   newRevocationList(),
+	}
+	if cfg.IntrospectionCacheTTL > 0 {
+		v.cache = NewIntrospectionCache(oryClient.IntrospectToken,
+			time.Duration(cfg.IntrospectionCacheTTL)*time.Second,
+			time.Duration(cfg.IntrospectionCacheNegativeTTL)*time.Second,
+			cfg.IntrospectionCacheSize)
+		log.Printf("Introspection cache enabled (ttl %ds, max %d entries)", cfg.IntrospectionCacheTTL, cfg.IntrospectionCacheSize)
+	}
+	return v
+}
+
+// Validate checks a token and returns its claims in introspection form.
+// A JWT that fails local verification is rejected, never introspected.
+func (v *TokenValidator) Validate(token string) (*IntrospectionResponse, error) {
+	if v.mode == ValidationIntrospection {
+		return v.introspect(token)
+	}
+
+	if _, isJWT := parseJWTHeader(token); isJWT {
+		return v.validateJWT(token)
+	}
+	if v.mode == ValidationJWT {
+		return nil, errors.New("token is not a JWT")
+	}
+	return v.introspect(token)
+}
+
+func (v *TokenValidator) introspect(token string) (*IntrospectionResponse, error) {
+	if v.cache != nil {
+		return v.cache.Introspect(token)
+	}
+	return v.oryClient.IntrospectToken(token)
+}
+
+// Forget drops any cached introspection result for a revoked token. A JWT
+// access token is verified locally, so its jti is put on the revocation
+// list instead. Only tokens with a valid signature are listed, so nobody
+// can revoke a token by guessing its jti.
+func (v *TokenValidator) Forget(token string) {
+	if v.cache != nil {
+		v.cache.Forget(token)
+	}
+	if v.mode == ValidationIntrospection {
+		return
+	}
+	if _, isJWT := parseJWTHeader(token); !isJWT {
+		return
+	}
+	claims, err := verifyJWT(token, v.keys.key)
+	if err != nil {
+		return
+	}
+	jti, _ := claims["jti"].(string)
+	exp, ok := numericClaim(claims, "exp")
+	if jti != "" && ok {
+		v.revoked.revokeToken(jti, time.Unix(exp, 0))
+	}
+}
+
+// ForgetGrant rejects JWT access tokens issued to clientID (every client
+// when empty) for subject up to now, after their consent was revoked
+func (v *TokenValidator) ForgetGrant(subject, clientID string) {
+	if v.mode != ValidationIntrospection {
+		v.revoked.revokeGrant(subject, clientID)
+	}
+}
+
+// IntrospectionStats reports the introspection cache counters; ok is false
+// when the cache is disabled
+func (v *TokenValidator) IntrospectionStats() (stats IntrospectionCacheStats, ok bool) {
+	if v.cache == nil {
+		return IntrospectionCacheStats{}, false
+	}
+	return v.cache.Stats(), true
+}
+
+func (v *TokenValidator) validateJWT(token string) (*IntrospectionResponse, error) {
+	claims, err := verifyJWT(token, v.keys.key)
+	if err != nil {
+		return nil, fmt.Errorf("JWT verification failed: %w", err)
+	}
+
+	now := time.Now()
+	exp, ok := numericClaim(claims, "exp")
+	if !ok {
+		return nil, errors.New("JWT has no exp claim")
+	}
+	if now.After(time.Unix(exp, 0).Add(clockSkew)) {
+		return nil, errors.New("JWT has expired")
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(clockSkew).Before(time.Unix(nbf, 0)) {
+		return nil, errors.New("JWT is not valid yet")
+	}
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != strings.TrimSuffix(v.issuer, "/") {
+		return nil, fmt.Errorf("JWT issuer %q is not trusted", iss)
+	}
+	if !hasAudience(claims["aud"], v.audience) {
+		return nil, fmt.Errorf("JWT is not intended for audience %q", v.audience)
+	}
+	// Hydra signs ID tokens with the same keys, so tell them apart by their
+	// claims: access tokens carry client_id and scp, ID tokens at_hash/nonce
+	if _, ok := claims["client_id"].(string); !ok {
+		return nil, errors.New("JWT has no client_id claim (not an access token)")
+	}
+	if _, ok := claims["scp"]; !ok {
+		return nil, errors.New("JWT has no scp claim (not an access token)")
+	}
+	if _, ok := claims["at_hash"]; ok {
+		return nil, errors.New("JWT is an ID token, not an access token")
+	}
+	if _, ok := claims["nonce"]; ok {
+		return nil, errors.New("JWT is an ID token, not an access token")
+	}
+
+	resp := &IntrospectionResponse{
+		Active: true,
+		Exp:    exp,
+	}
+	resp.Sub, _ = claims["sub"].(string)
+	resp.ClientID, _ = claims["client_id"].(string)
+	jti, _ := claims["jti"].(string)
+	iat, _ := numericClaim(claims, "iat")
+	if v.revoked.revoked(jti, resp.Sub, resp.ClientID, iat) {
+		return nil, errors.New("JWT has been revoked")
+	}
+	resp.Email, _ = claims["email"].(string)
+	if ext, ok := claims["ext"].(map[string]interface{}); ok && resp.Email == "" {
+		// Hydra nests session claims under ext
+		resp.Email, _ = ext["email"].(string)
+	}
+
+	// Hydra puts scopes in scp (array)
+	if scp, ok := claims["scp"].([]interface{}); ok {
+		scopes := make([]string, 0, len(scp))
+		for _, s := range scp {
+			if str, ok := s.(string); ok {
+				scopes = append(scopes, str)
+			}
+		}
+		resp.Scope = strings.Join(scopes, " ")
+	}
+
+	return resp, nil
+}
+
+func numericClaim(claims map[string]interface{}, name string) (int64, bool) {
+	value, ok := claims[name].(float64)
+	if !ok {
+		return 0, false
+	}
+	return int64(value), true
+}
+
+// hasAudience reports whether aud (a string or an array) contains want
+func hasAudience(aud interface{}, want string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == want
+	case []interface{}:
+		for _, a := range aud {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
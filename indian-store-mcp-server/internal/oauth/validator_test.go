@@ -0,0 +1,149 @@
+package oauth
+
+import (
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://auth.example.com/"
+	testAudience = "https://mcp.example.com"
+)
+
+func newTestValidator(t *testing.T, keys *testKeys) *TokenValidator {
+	t.Helper()
+	set := keys.jwks()
+	fetches := 0
+	oryClient := hydraJWKS(t, &set, &fetches)
+	return &TokenValidator{
+		mode:      ValidationJWT,
+		issuer:    testIssuer,
+		audience:  testAudience,
+		oryClient: oryClient,
+		keys:      newJWKSCache(oryClient, time.Hour),
+		revoked:   newRevocationList(),
+	}
+}
+
+// accessTokenClaims are the claims of a Hydra JWT access token with
+// changes applied (a nil value removes the claim)
+func accessTokenClaims(changes map[string]interface{}) map[string]interface{} {
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":       testIssuer,
+		"sub":       "user@example.com",
+		"aud":       []string{testAudience},
+		"client_id": "client-1",
+		"scp":       []string{"openid", "stores:read"},
+		"jti":       "token-1",
+		"iat":       now.Unix(),
+		"exp":       now.Add(time.Hour).Unix(),
+		"ext":       map[string]interface{}{"email": "user@example.com"},
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+	}
+	return claims
+}
+
+func TestValidateJWT(t *testing.T) {
+	keys := newTestKeys(t)
+	v := newTestValidator(t, keys)
+	hourAgo := time.Now().Add(-time.Hour).Unix()
+
+	tests := []struct {
+		name   string
+		alg    string
+		kid    string
+		claims map[string]interface{}
+		valid  bool
+	}{
+		{"valid", "RS256", "rsa", nil, true},
+		{"valid EdDSA", "EdDSA", "ed25519", nil, true},
+		{"audience as string", "RS256", "rsa", map[string]interface{}{"aud": testAudience}, true},
+		{"issuer without trailing slash", "RS256", "rsa", map[string]interface{}{"iss": "https://auth.example.com"}, true},
+		{"alg none", "none", "rsa", nil, false},
+		{"wrong alg", "ES256", "rsa", nil, false},
+		{"wrong kid", "RS256", "retired", nil, false},
+		{"expired", "RS256", "rsa", map[string]interface{}{"exp": hourAgo}, false},
+		{"expired within clock skew", "RS256", "rsa", map[string]interface{}{"exp": time.Now().Add(-10 * time.Second).Unix()}, true},
+		{"no exp", "RS256", "rsa", map[string]interface{}{"exp": nil}, false},
+		{"not valid yet", "RS256", "rsa", map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()}, false},
+		{"wrong issuer", "RS256", "rsa", map[string]interface{}{"iss": "https://evil.example.com/"}, false},
+		{"wrong audience", "RS256", "rsa", map[string]interface{}{"aud": []string{"https://other.example.com"}}, false},
+		{"no audience", "RS256", "rsa", map[string]interface{}{"aud": nil}, false},
+		{"ID token", "RS256", "rsa", map[string]interface{}{"client_id": nil, "scp": nil, "at_hash": "abc", "nonce": "n"}, false},
+		{"ID token with access token claims", "RS256", "rsa", map[string]interface{}{"at_hash": "abc"}, false},
+		{"nonce", "RS256", "rsa", map[string]interface{}{"nonce": "n"}, false},
+		{"no client_id", "RS256", "rsa", map[string]interface{}{"client_id": nil}, false},
+		{"no scp", "RS256", "rsa", map[string]interface{}{"scp": nil}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := v.Validate(keys.sign(t, tt.alg, tt.kid, accessTokenClaims(tt.claims)))
+			if tt.valid && err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("Validate() accepted the token")
+			}
+			if tt.valid && (resp.Sub != "user@example.com" || resp.ClientID != "client-1" ||
+				resp.Email != "user@example.com" || resp.Scope != "openid stores:read") {
+				t.Errorf("Validate() = %+v", resp)
+			}
+		})
+	}
+
+	t.Run("opaque token in jwt mode", func(t *testing.T) {
+		if _, err := v.Validate("ory_at_opaque.token"); err == nil {
+			t.Error("Validate() accepted an opaque token")
+		}
+	})
+}
+
+func TestValidateJWTRevocation(t *testing.T) {
+	keys := newTestKeys(t)
+	v := newTestValidator(t, keys)
+	issued := time.Now().Add(-time.Minute).Unix()
+
+	revokedToken := keys.sign(t, "RS256", "rsa", accessTokenClaims(map[string]interface{}{"jti": "revoked", "iat": issued}))
+	otherToken := keys.sign(t, "RS256", "rsa", accessTokenClaims(map[string]interface{}{"jti": "other", "iat": issued}))
+	v.Forget(revokedToken)
+	if _, err := v.Validate(revokedToken); err == nil {
+		t.Error("Validate() accepted a revoked jti")
+	}
+	if _, err := v.Validate(otherToken); err != nil {
+		t.Errorf("Validate() of another token = %v", err)
+	}
+
+	// A jti cannot be revoked without a valid signature
+	forged := newTestKeys(t).sign(t, "RS256", "rsa", accessTokenClaims(map[string]interface{}{"jti": "other"}))
+	v.Forget(forged)
+	if _, err := v.Validate(otherToken); err != nil {
+		t.Errorf("Validate() after forged revocation = %v", err)
+	}
+
+	// Revoking the grant rejects the client's earlier tokens only
+	v.ForgetGrant("user@example.com", "client-1")
+	if _, err := v.Validate(otherToken); err == nil {
+		t.Error("Validate() accepted a token of a revoked grant")
+	}
+	otherClient := keys.sign(t, "RS256", "rsa", accessTokenClaims(map[string]interface{}{"client_id": "client-2", "iat": issued}))
+	if _, err := v.Validate(otherClient); err != nil {
+		t.Errorf("Validate() of another client's token = %v", err)
+	}
+	later := keys.sign(t, "RS256", "rsa", accessTokenClaims(map[string]interface{}{"jti": "new", "iat": time.Now().Add(time.Second).Unix()}))
+	if _, err := v.Validate(later); err != nil {
+		t.Errorf("Validate() of a token issued after the revocation = %v", err)
+	}
+
+	// Revoking every client
+	v.ForgetGrant("user@example.com", "")
+	if _, err := v.Validate(otherClient); err == nil {
+		t.Error("Validate() accepted a token after all grants were revoked")
+	}
+}
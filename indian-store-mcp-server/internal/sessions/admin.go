@@ -0,0 +1,62 @@
+package sessions
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// AdminHandler serves /admin/sessions?email=...: GET lists a user's
+// sessions, DELETE revokes one (&id=<handle>) or all of them
+func AdminHandler(store SessionStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := r.URL.Query().Get("email")
+		if email == "" {
+			http.Error(w, "email is required", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			list, err := store.List(email)
+			if err != nil {
+				log.Printf("Failed to list sessions of %s: %v", email, err)
+				http.Error(w, "Internal error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"sessions": list})
+
+		case http.MethodDelete:
+			revoked := 0
+			if handle := r.URL.Query().Get("id"); handle != "" {
+				found, err := store.Revoke(email, handle)
+				if err != nil {
+					log.Printf("Failed to revoke session of %s: %v", email, err)
+					http.Error(w, "Internal error", http.StatusInternalServerError)
+					return
+				}
+				if !found {
+					http.NotFound(w, r)
+					return
+				}
+				revoked = 1
+			} else {
+				n, err := store.RevokeAll(email)
+				if err != nil {
+					log.Printf("Failed to revoke sessions of %s: %v", email, err)
+					http.Error(w, "Internal error", http.StatusInternalServerError)
+					return
+				}
+				revoked = n
+			}
+			log.Printf("Revoked %d sessions of %s", revoked, email)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]int{"revoked": revoked})
+
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package sessions
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps sessions in process memory. Sessions are lost on
+// restart and not shared between replicas; use it for local development.
+type MemoryStore struct {
+	ttl      time.Duration
+	sessions map[string]*Session // by handle
+	mu       sync.RWMutex
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		ttl:      ttl,
+		sessions: make(map[string]*Session),
+	}
+}
+
+func (s *MemoryStore) Create(email string, meta Metadata) (string, *Session, error) {
+	id, handle, err := newSessionID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	session := &Session{
+		Handle:    handle,
+		Email:     email,
+		UserAgent: truncate(meta.UserAgent, 512),
+		IP:        truncate(meta.IP, 64),
+		AMR:       meta.AMR,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+
+	s.mu.Lock()
+	s.sessions[handle] = session
+	s.mu.Unlock()
+
+	copied := *session
+	return id, &copied, nil
+}
+
+func (s *MemoryStore) Get(id string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[hashID(id)]
+	if !ok || time.Now().After(session.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	delete(s.sessions, hashID(id))
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) List(email string) ([]Session, error) {
+	now := time.Now()
+	list := []Session{}
+
+	s.mu.RLock()
+	for _, session := range s.sessions {
+		if session.Email == email && now.Before(session.ExpiresAt) {
+			list = append(list, *session)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list, nil
+}
+
+func (s *MemoryStore) Revoke(email, handle string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[handle]
+	if !ok || session.Email != email {
+		return false, nil
+	}
+	delete(s.sessions, handle)
+	return true, nil
+}
+
+func (s *MemoryStore) RevokeAll(email string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for handle, session := range s.sessions {
+		if session.Email == email {
+			delete(s.sessions, handle)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) DeleteExpired() (int, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for handle, session := range s.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(s.sessions, handle)
+			n++
+		}
+	}
+	return n, nil
+}
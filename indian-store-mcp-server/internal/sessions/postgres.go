@@ -0,0 +1,146 @@
+package sessions
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// PostgresStore keeps sessions in PostgreSQL so they survive restarts and
+// are shared by all replicas
+type PostgresStore struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+// NewPostgresStore creates the store on the shared database connection
+func NewPostgresStore(db *sql.DB, ttl time.Duration) (*PostgresStore, error) {
+	store := &PostgresStore{db: db, ttl: ttl}
+
+	// Create table if not exists
+	if err := store.createTable(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// createTable creates the login_sessions table
+func (s *PostgresStore) createTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS login_sessions (
+		id_hash CHAR(64) PRIMARY KEY,
+		email VARCHAR(255) NOT NULL,
+		user_agent VARCHAR(512) NOT NULL DEFAULT '',
+		ip VARCHAR(64) NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS login_sessions_email_idx ON login_sessions (email);
+	CREATE INDEX IF NOT EXISTS login_sessions_expires_at_idx ON login_sessions (expires_at);
+	ALTER TABLE login_sessions ADD COLUMN IF NOT EXISTS amr VARCHAR(64) NOT NULL DEFAULT ''`
+
+	_, err := s.db.Exec(query)
+	return err
+}
+
+func (s *PostgresStore) Create(email string, meta Metadata) (string, *Session, error) {
+	id, handle, err := newSessionID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	session := &Session{
+		Handle:    handle,
+		Email:     email,
+		UserAgent: truncate(meta.UserAgent, 512),
+		IP:        truncate(meta.IP, 64),
+		AMR:       meta.AMR,
+	}
+	query := `
+	INSERT INTO login_sessions (id_hash, email, user_agent, ip, amr, expires_at)
+	VALUES ($1, $2, $3, $4, $5, NOW() + $6::integer * INTERVAL '1 second')
+	RETURNING created_at, expires_at`
+	err = s.db.QueryRow(query, handle, email, session.UserAgent, session.IP,
+		truncate(strings.Join(meta.AMR, " "), 64), int(s.ttl.Seconds())).
+		Scan(&session.CreatedAt, &session.ExpiresAt)
+	if err != nil {
+		return "", nil, err
+	}
+	return id, session, nil
+}
+
+func (s *PostgresStore) Get(id string) (*Session, error) {
+	query := `
+	SELECT id_hash, email, user_agent, ip, amr, created_at, expires_at
+	FROM login_sessions WHERE id_hash = $1 AND expires_at > NOW()`
+
+	var session Session
+	var amr string
+	err := s.db.QueryRow(query, hashID(id)).Scan(&session.Handle, &session.Email,
+		&session.UserAgent, &session.IP, &amr, &session.CreatedAt, &session.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	session.AMR = strings.Fields(amr)
+	return &session, nil
+}
+
+func (s *PostgresStore) Delete(id string) error {
+	_, err := s.db.Exec(`DELETE FROM login_sessions WHERE id_hash = $1`, hashID(id))
+	return err
+}
+
+func (s *PostgresStore) List(email string) ([]Session, error) {
+	rows, err := s.db.Query(`
+	SELECT id_hash, email, user_agent, ip, amr, created_at, expires_at
+	FROM login_sessions WHERE email = $1 AND expires_at > NOW()
+	ORDER BY created_at DESC`, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Session{}
+	for rows.Next() {
+		var session Session
+		var amr string
+		if err := rows.Scan(&session.Handle, &session.Email, &session.UserAgent,
+			&session.IP, &amr, &session.CreatedAt, &session.ExpiresAt); err != nil {
+			return nil, err
+		}
+		session.AMR = strings.Fields(amr)
+		list = append(list, session)
+	}
+	return list, rows.Err()
+}
+
+func (s *PostgresStore) Revoke(email, handle string) (bool, error) {
+	result, err := s.db.Exec(`DELETE FROM login_sessions WHERE id_hash = $1 AND email = $2`, handle, email)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (s *PostgresStore) RevokeAll(email string) (int, error) {
+	result, err := s.db.Exec(`DELETE FROM login_sessions WHERE email = $1`, email)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+func (s *PostgresStore) DeleteExpired() (int, error) {
+	result, err := s.db.Exec(`DELETE FROM login_sessions WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,90 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+)
+
+// ErrNotFound is returned for unknown, expired or revoked sessions
+var ErrNotFound = errors.New("session not found")
+
+// Session is a signed-in browser on the login and account pages
+type Session struct {
+	Handle    string    `json:"id"` // SHA-256 of the session ID; safe to show and used to revoke
+	Email     string    `json:"email"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	AMR       []string  `json:"amr,omitempty"` // how the user signed in (RFC 8176), e.g. pwd, otp
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Metadata describes the browser a session is created for
+type Metadata struct {
+	UserAgent string
+	IP        string
+	AMR       []string // authentication methods used
+}
+
+// SessionStore keeps login sessions. Stores only ever see the SHA-256 of a
+// session ID, so a leaked table or dump cannot be replayed as cookies.
+type SessionStore interface {
+	// Create starts a session and returns the ID to put in the cookie
+	Create(email string, meta Metadata) (string, *Session, error)
+	// Get returns the live session for a cookie value
+	Get(id string) (*Session, error)
+	// Delete ends the session with the given cookie value
+	Delete(id string) error
+	// List returns a user's live sessions, newest first
+	List(email string) ([]Session, error)
+	// Revoke ends one of a user's sessions by handle, reporting whether it existed
+	Revoke(email, handle string) (bool, error)
+	// RevokeAll ends every session of a user
+	RevokeAll(email string) (int, error)
+	// DeleteExpired removes expired sessions
+	DeleteExpired() (int, error)
+}
+
+// RunJanitor removes expired sessions on every tick
+func RunJanitor(store SessionStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n, err := store.DeleteExpired()
+		if err != nil {
+			log.Printf("Session janitor: %v", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("Session janitor: removed %d expired sessions", n)
+		}
+	}
+}
+
+// newSessionID returns a random session ID and its handle
+func newSessionID() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	id := hex.EncodeToString(b)
+	return id, hashID(id), nil
+}
+
+func hashID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncate keeps client-supplied metadata within the column sizes
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
@@ -0,0 +1,53 @@
+package stores
+
+import "regexp"
+
+// PincodePattern matches a six-digit Indian postal index number
+const PincodePattern = `^[1-9][0-9]{5}$`
+
+var pincodeRE = regexp.MustCompile(PincodePattern)
+
+// pincodeStates maps the first two digits of a pincode (the postal circle)
+// to the state it serves. Names match the lower-case states in cityStates.
+var pincodeStates = map[string]string{
+	"11": "delhi",
+	"12": "haryana", "13": "haryana",
+	"14": "punjab", "15": "punjab", "16": "punjab",
+	"17": "himachal pradesh",
+	"18": "jammu and kashmir", "19": "jammu and kashmir",
+	"20": "uttar pradesh", "21": "uttar pradesh", "22": "uttar pradesh", "23": "uttar pradesh",
+	"24": "uttarakhand", "25": "uttar pradesh", "26": "uttarakhand", "27": "uttar pradesh", "28": "uttar pradesh",
+	"30": "rajasthan", "31": "rajasthan", "32": "rajasthan", "33": "rajasthan", "34": "rajasthan",
+	"36": "gujarat", "37": "gujarat", "38": "gujarat", "39": "gujarat",
+	"40": "maharashtra", "41": "maharashtra", "42": "maharashtra", "43": "maharashtra", "44": "maharashtra",
+	"45": "madhya pradesh", "46": "madhya pradesh", "47": "madhya pradesh", "48": "madhya pradesh",
+	"49": "chhattisgarh",
+	"50": "telangana",
+	"51": "andhra pradesh", "52": "andhra pradesh", "53": "andhra pradesh",
+	"56": "karnataka", "57": "karnataka", "58": "karnataka", "59": "karnataka",
+	"60": "tamil nadu", "61": "tamil nadu", "62": "tamil nadu", "63": "tamil nadu", "64": "tamil nadu",
+	"67": "kerala", "68": "kerala", "69": "kerala",
+	"70": "west bengal", "71": "west bengal", "72": "west bengal", "73": "west bengal", "74": "west bengal",
+	"75": "odisha", "76": "odisha", "77": "odisha",
+	"78": "assam",
+	"79": "north east",
+	"80": "bihar", "81": "jharkhand", "82": "jharkhand", "83": "jharkhand", "84": "bihar", "85": "bihar",
+}
+
+// pincodeOverrides covers regions that do not follow their circle's state
+var pincodeOverrides = map[string]string{
+	"160": "chandigarh",
+	"403": "goa",
+}
+
+// StateForPincode returns the state a pincode belongs to
+func StateForPincode(pincode string) (string, bool) {
+	if !pincodeRE.MatchString(pincode) {
+		return "", false
+	}
+	if state, ok := pincodeOverrides[pincode[:3]]; ok {
+		return state, true
+	}
+	state, ok := pincodeStates[pincode[:2]]
+	return state, ok
+}
@@ -0,0 +1,195 @@
+package stores
+
+import (
+	"sort"
+	"strings"
+)
+
+// Sort orders accepted by Search
+var SortOrders = []string{"relevance", "name", "newest", "oldest"}
+
+// cityStates maps the cities used in DeliveryRegions to their state so a
+// search by state finds stores that only serve some of its cities
+var cityStates = map[string]string{
+	"delhi":      "delhi",
+	"mumbai":     "maharashtra",
+	"pune":       "maharashtra",
+	"bengaluru":  "karnataka",
+	"hyderabad":  "telangana",
+	"chennai":    "tamil nadu",
+	"kolkata":    "west bengal",
+	"ahmedabad":  "gujarat",
+	"gurugram":   "haryana",
+	"noida":      "uttar pradesh",
+	"jaipur":     "rajasthan",
+	"lucknow":    "uttar pradesh",
+	"chandigarh": "chandigarh",
+	"kochi":      "kerala",
+}
+
+// SearchQuery filters and orders a catalog search. Zero values mean "any".
+type SearchQuery struct {
+	Query    string // free text matched against name, categories, description
+	Category string
+	Location string // city or state the order ships to
+	COD      *bool
+	UPI      *bool
+	Sort     string // one of SortOrders; defaults to relevance
+	Page     int    // 1-based
+	PageSize int
+}
+
+// SearchHit is a store that matched a search, with its relevance score
+type SearchHit struct {
+	Store *Store  `json:"store"`
+	Score float64 `json:"score"`
+}
+
+// SearchPage is one page of search results
+type SearchPage struct {
+	Hits     []SearchHit `json:"results"`
+	Total    int         `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+	HasMore  bool        `json:"has_more"`
+}
+
+// Search filters, ranks and paginates the catalog. The catalog is small
+// enough that ranking happens in Go over a single query.
+func (c *Catalog) Search(q SearchQuery) (*SearchPage, error) {
+	all, err := c.ListStores()
+	if err != nil {
+		return nil, err
+	}
+
+	terms := strings.Fields(strings.ToLower(q.Query))
+
+	var hits []SearchHit
+	for _, store := range all {
+		if q.Category != "" && !containsFold(store.Categories, q.Category) {
+			continue
+		}
+		if q.Location != "" && !store.DeliversTo(q.Location) {
+			continue
+		}
+		if q.COD != nil && store.CODAvailable != *q.COD {
+			continue
+		}
+		if q.UPI != nil && store.AcceptsPayment(PaymentUPI) != *q.UPI {
+			continue
+		}
+
+		score := relevance(store, terms)
+		if len(terms) > 0 && score == 0 {
+			continue
+		}
+		hits = append(hits, SearchHit{Store: store, Score: score})
+	}
+
+	sortHits(hits, q.Sort)
+
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 {
+		q.PageSize = 10
+	}
+
+	page := &SearchPage{
+		Hits:     []SearchHit{},
+		Total:    len(hits),
+		Page:     q.Page,
+		PageSize: q.PageSize,
+	}
+	start := (q.Page - 1) * q.PageSize
+	if start < len(hits) {
+		end := start + q.PageSize
+		if end > len(hits) {
+			end = len(hits)
+		}
+		page.Hits = hits[start:end]
+		page.HasMore = end < len(hits)
+	}
+
+	return page, nil
+}
+
+// relevance scores how well a store matches the query terms
+func relevance(store *Store, terms []string) float64 {
+	name := strings.ToLower(store.Name)
+	description := strings.ToLower(store.Description)
+	parent := strings.ToLower(store.ParentCompany)
+
+	var score float64
+	for _, term := range terms {
+		switch {
+		case name == term || store.Slug == term:
+			score += 10
+		case strings.Contains(name, term):
+			score += 5
+		}
+		if containsFold(store.Categories, term) {
+			score += 4
+		}
+		if strings.Contains(description, term) {
+			score += 2
+		}
+		if strings.Contains(parent, term) {
+			score += 2
+		}
+		if strings.EqualFold(store.HQCity, term) {
+			score += 1
+		}
+	}
+	return score
+}
+
+func sortHits(hits []SearchHit, order string) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := hits[i].Store, hits[j].Store
+		switch order {
+		case "name":
+			return a.Name < b.Name
+		case "newest":
+			return a.FoundedYear > b.FoundedYear
+		case "oldest":
+			return a.FoundedYear < b.FoundedYear
+		default:
+			if hits[i].Score != hits[j].Score {
+				return hits[i].Score > hits[j].Score
+			}
+			return a.Name < b.Name
+		}
+	})
+}
+
+// DeliversTo reports whether the store ships to a city or state
+func (s *Store) DeliversTo(location string) bool {
+	location = strings.ToLower(strings.TrimSpace(location))
+	for _, region := range s.DeliveryRegions {
+		region = strings.ToLower(region)
+		if region == strings.ToLower(RegionAllIndia) || region == location || cityStates[region] == location {
+			return true
+		}
+	}
+	return false
+}
+
+// AcceptsPayment reports whether the store supports the given payment method
+func (s *Store) AcceptsPayment(method string) bool {
+	for _, m := range s.PaymentMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package stores
+
+// metroCities is the delivery footprint used for quick-commerce and
+// grocery stores that do not ship nationwide
+var metroCities = []string{
+	"Delhi", "Mumbai", "Bengaluru", "Hyderabad", "Chennai", "Kolkata", "Pune",
+	"Ahmedabad", "Gurugram", "Noida", "Jaipur", "Lucknow", "Chandigarh", "Kochi",
+}
+
+var allPayments = []string{
+	PaymentUPI, PaymentCreditCard, PaymentDebitCard, PaymentNetBanking,
+	PaymentEMI, PaymentWallet, PaymentCOD,
+}
+
+// seedStores is the initial catalog loaded into an empty stores table.
+// Further changes are made in the database and need no redeploy.
+var seedStores = []Store{
+	{
+		Slug:            "flipkart",
+		Name:            "Flipkart",
+		URL:             "https://www.flipkart.com",
+		Description:     "E-commerce platform offering electronics, fashion, home essentials",
+		Categories:      []string{"electronics", "fashion", "home", "appliances", "furniture", "grocery", "books", "beauty"},
+		ParentCompany:   "Walmart",
+		FoundedYear:     2007,
+		HQCity:          "Bengaluru",
+		PaymentMethods:  append([]string{PaymentPayLater}, allPayments...),
+		DeliveryRegions: []string{RegionAllIndia},
+		CODAvailable:    true,
+		ReturnPolicy:    "Most items can be returned or replaced within 7-10 days of delivery; some electronics are replacement-only.",
+	},
+	{
+		Slug:            "amazon-india",
+		Name:            "Amazon India",
+		URL:             "https://www.amazon.in",
+		Description:     "Global e-commerce platform with wide product range",
+		Categories:      []string{"electronics", "fashion", "home", "appliances", "grocery", "books", "beauty", "pharmacy", "furniture"},
+		ParentCompany:   "Amazon.com, Inc.",
+		FoundedYear:     2013,
+		HQCity:          "Bengaluru",
+		PaymentMethods:  append([]string{PaymentPayLater}, allPayments...),
+		DeliveryRegions: []string{RegionAllIndia},
+		CODAvailable:    true,
+		ReturnPolicy:    "Most items are returnable within 7-10 days of delivery; category-specific exceptions apply.",
+	},
+	{
+		Slug:            "reliance-digital",
+		Name:            "Reliance Digital",
+		URL:             "https://www.reliancedigital.in",
+		Description:     "Electronics and appliances retailer",
+		Categories:      []string{"electronics", "appliances"},
+		ParentCompany:   "Reliance Retail",
+		FoundedYear:     2007,
+		HQCity:          "Mumbai",
+		PaymentMethods:  []string{PaymentUPI, PaymentCreditCard, PaymentDebitCard, PaymentNetBanking, PaymentEMI, PaymentCOD},
+		DeliveryRegions: []string{RegionAllIndia},
+		CODAvailable:    true,
+		ReturnPolicy:    "Eligible products can be returned within 7 days after inspection; installed appliances are service-only.",
+	},
+	{
+		Slug:            "myntra",
+		Name:            "Myntra",
+		URL:             "https://www.myntra.com",
+		Description:     "Fashion and lifestyle e-commerce platform",
+		Categories:      []string{"fashion", "beauty", "home"},
+		ParentCompany:   "Flipkart",
+		FoundedYear:     2007,
+		HQCity:          "Bengaluru",
+		PaymentMethods:  allPayments,
+		DeliveryRegions: []string{RegionAllIndia},
+		CODAvailable:    true,
+		ReturnPolicy:    "Returns or exchanges within 7-30 days depending on the product; innerwear and cosmetics are non-returnable.",
+	},
+	{
+		Slug:            "snapdeal",
+		Name:            "Snapdeal",
+		URL:             "https://www.snapdeal.com",
+		Description:     "E-commerce platform with various product categories",
+		Categories:      []string{"fashion", "home", "electronics", "general"},
+		ParentCompany:   "AceVector Group",
+		FoundedYear:     2010,
+		HQCity:          "New Delhi",
+		PaymentMethods:  []string{PaymentUPI, PaymentCreditCard, PaymentDebitCard, PaymentNetBanking, PaymentWallet, PaymentCOD},
+		DeliveryRegions: []string{RegionAllIndia},
+		CODAvailable:    true,
+		ReturnPolicy:    "Most items can be returned within 7 days of delivery.",
+	},
+	{
+		Slug:            "tata-cliq",
+		Name:            "Tata CLiQ",
+		URL:             "https://www.tatacliq.com",
+		Description:     "Digital commerce platform by Tata Group",
+		Categories:      []string{"electronics", "fashion", "home", "appliances", "beauty"},
+		ParentCompany:   "Tata Digital",
+		FoundedYear:     2016,
+		HQCity:          "Mumbai",
+		PaymentMethods:  allPayments,
+		DeliveryRegions: []string{RegionAllIndia},
+		CODAvailable:    true,
+		ReturnPolicy:    "Returns within 10-30 days depending on the category; electronics are replacement-only.",
+	},
+	{
+		Slug:            "nykaa",
+		Name:            "Nykaa",
+		URL:             "https://www.nykaa.com",
+		Description:     "Beauty, wellness and fashion retailer",
+		Categories:      []string{"beauty", "fashion"},
+		ParentCompany:   "FSN E-Commerce Ventures",
+		FoundedYear:     2012,
+		HQCity:          "Mumbai",
+		PaymentMethods:  allPayments,
+		DeliveryRegions: []string{RegionAllIndia},
+		CODAvailable:    true,
+		ReturnPolicy:    "Unused products in original packaging can be returned within 15 days.",
+	},
+	{
+		Slug:            "ajio",
+		Name:            "AJIO",
+		URL:             "https://www.ajio.com",
+		Description:     "Fashion and lifestyle store by Reliance",
+		Categories:      []string{"fashion", "home"},
+		ParentCompany:   "Reliance Retail",
+		FoundedYear:     2016,
+		HQCity:          "Mumbai",
+		PaymentMethods:  allPayments,
+		DeliveryRegions: []string{RegionAllIndia},
+		CODAvailable:    true,
+		ReturnPolicy:    "Returns within 15 days of delivery for most products.",
+	},
+	{
+		Slug:            "meesho",
+		Name:            "Meesho",
+		URL:             "https://www.meesho.com",
+		Description:     "Value e-commerce marketplace for small sellers",
+		Categories:      []string{"fashion", "home", "beauty", "general"},
+		ParentCompany:   "Fashnear Technologies",
+		FoundedYear:     2015,
+		HQCity:          "Bengaluru",
+		PaymentMethods:  []string{PaymentUPI, PaymentCreditCard, PaymentDebitCard, PaymentNetBanking, PaymentCOD},
+		DeliveryRegions: []string{RegionAllIndia},
+		CODAvailable:    true,
+		ReturnPolicy:    "Returns or exchanges within 7 days of delivery.",
+	},
+	{
+		Slug:            "bigbasket",
+		Name:            "BigBasket",
+		URL:             "https://www.bigbasket.com",
+		Description:     "Online grocery supermarket",
+		Categories:      []string{"grocery", "home", "beauty"},
+		ParentCompany:   "Tata Digital",
+		FoundedYear:     2011,
+		HQCity:          "Bengaluru",
+		PaymentMethods:  allPayments,
+		DeliveryRegions: metroCities,
+		CODAvailable:    true,
+		ReturnPolicy:    "Fresh produce can be returned at the doorstep; other items within 7 days if unopened.",
+	},
+	{
+		Slug:            "blinkit",
+		Name:            "Blinkit",
+		URL:             "https://blinkit.com",
+		Description:     "Quick-commerce delivery of groceries and essentials in minutes",
+		Categories:      []string{"grocery", "beauty", "home", "electronics"},
+		ParentCompany:   "Eternal (Zomato)",
+		FoundedYear:     2013,
+		HQCity:          "Gurugram",
+		PaymentMethods:  []string{PaymentUPI, PaymentCreditCard, PaymentDebitCard, PaymentNetBanking, PaymentWallet, PaymentCOD},
+		DeliveryRegions: metroCities,
+		CODAvailable:    true,
+		ReturnPolicy:    "Damaged or missing items must be reported in the app shortly after delivery.",
+	},
+	{
+		Slug:            "tata-1mg",
+		Name:            "Tata 1mg",
+		URL:             "https://www.1mg.com",
+		Description:     "Online pharmacy, lab tests and health products",
+		Categories:      []string{"pharmacy", "beauty"},
+		ParentCompany:   "Tata Digital",
+		FoundedYear:     2015,
+		HQCity:          "Gurugram",
+		PaymentMethods:  allPayments,
+		DeliveryRegions: []string{RegionAllIndia},
+		CODAvailable:    true,
+		ReturnPolicy:    "Unopened items can be returned within 7 days; prescription medicines are returnable only if damaged or incorrect.",
+	},
+	{
+		Slug:            "pharmeasy",
+		Name:            "PharmEasy",
+		URL:             "https://pharmeasy.in",
+		Description:     "Online pharmacy and healthcare platform",
+		Categories:      []string{"pharmacy"},
+		ParentCompany:   "API Holdings",
+		FoundedYear:     2015,
+		HQCity:          "Mumbai",
+		PaymentMethods:  allPayments,
+		DeliveryRegions: []string{RegionAllIndia},
+		CODAvailable:    true,
+		ReturnPolicy:    "Damaged, expired or incorrect items can be returned within 7 days of delivery.",
+	},
+	{
+		Slug:            "pepperfry",
+		Name:            "Pepperfry",
+		URL:             "https://www.pepperfry.com",
+		Description:     "Furniture and home decor marketplace",
+		Categories:      []string{"furniture", "home"},
+		ParentCompany:   "Trendsutra Platform Services",
+		FoundedYear:     2012,
+		HQCity:          "Mumbai",
+		PaymentMethods:  []string{PaymentUPI, PaymentCreditCard, PaymentDebitCard, PaymentNetBanking, PaymentEMI},
+		DeliveryRegions: []string{RegionAllIndia},
+		CODAvailable:    false,
+		ReturnPolicy:    "Returns accepted within 7 days for damaged or defective products; assembled furniture cannot be returned.",
+	},
+	{
+		Slug:            "croma",
+		Name:            "Croma",
+		URL:             "https://www.croma.com",
+		Description:     "Electronics retailer by the Tata Group",
+		Categories:      []string{"electronics", "appliances"},
+		ParentCompany:   "Infiniti Retail (Tata Group)",
+		FoundedYear:     2006,
+		HQCity:          "Mumbai",
+		PaymentMethods:  []string{PaymentUPI, PaymentCreditCard, PaymentDebitCard, PaymentNetBanking, PaymentEMI, PaymentCOD},
+		DeliveryRegions: []string{RegionAllIndia},
+		CODAvailable:    true,
+		ReturnPolicy:    "Eligible products can be returned within 7 days in original condition.",
+	},
+}
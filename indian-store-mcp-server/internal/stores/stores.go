@@ -0,0 +1,241 @@
+package stores
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Product categories a store can be listed under
+var Categories = []string{
+	"electronics",
+	"fashion",
+	"grocery",
+	"pharmacy",
+	"furniture",
+	"beauty",
+	"home",
+	"appliances",
+	"books",
+	"general",
+}
+
+// Payment method identifiers used in Store.PaymentMethods
+const (
+	PaymentUPI        = "upi"
+	PaymentCreditCard = "credit_card"
+	PaymentDebitCard  = "debit_card"
+	PaymentNetBanking = "net_banking"
+	PaymentEMI        = "emi"
+	PaymentWallet     = "wallet"
+	PaymentPayLater   = "pay_later"
+	PaymentCOD        = "cod"
+)
+
+// RegionAllIndia in DeliveryRegions means the store ships nationwide
+const RegionAllIndia = "All India"
+
+// Store is an Indian online store in the catalog
+type Store struct {
+	Slug            string    `json:"slug"`
+	Name            string    `json:"name"`
+	URL             string    `json:"url"`
+	Description     string    `json:"description"`
+	Categories      []string  `json:"categories"`
+	ParentCompany   string    `json:"parent_company"`
+	FoundedYear     int       `json:"founded_year"`
+	HQCity          string    `json:"hq_city"`
+	PaymentMethods  []string  `json:"payment_methods"`
+	DeliveryRegions []string  `json:"delivery_regions"`
+	CODAvailable    bool      `json:"cod_available"`
+	ReturnPolicy    string    `json:"return_policy"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Catalog manages the store catalog in PostgreSQL
+type Catalog struct {
+	db *sql.DB
+}
+
+// NewCatalog creates the catalog on the shared database connection
+func NewCatalog(db *sql.DB) (*Catalog, error) {
+	catalog := &Catalog{db: db}
+
+	// Create table if not exists
+	if err := catalog.createTable(); err != nil {
+		return nil, err
+	}
+	if err := catalog.createUpdatedAtTrigger(); err != nil {
+		return nil, err
+	}
+
+	// Seed the initial dataset if the catalog is empty
+	count, err := catalog.countStores()
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		log.Printf("No stores found, seeding catalog with %d stores", len(seedStores))
+		for i := range seedStores {
+			if err := catalog.AddStore(&seedStores[i]); err != nil {
+				log.Printf("Warning: Failed to seed store %s: %v", seedStores[i].Slug, err)
+			}
+		}
+	}
+
+	return catalog, nil
+}
+
+// createTable creates the stores table
+func (c *Catalog) createTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS stores (
+		slug VARCHAR(100) PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		url VARCHAR(255) NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		categories TEXT[] NOT NULL DEFAULT '{}',
+		parent_company VARCHAR(255) NOT NULL DEFAULT '',
+		founded_year INTEGER NOT NULL DEFAULT 0,
+		hq_city VARCHAR(100) NOT NULL DEFAULT '',
+		payment_methods TEXT[] NOT NULL DEFAULT '{}',
+		delivery_regions TEXT[] NOT NULL DEFAULT '{}',
+		cod_available BOOLEAN NOT NULL DEFAULT FALSE,
+		return_policy TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`
+
+	_, err := c.db.Exec(query)
+	return err
+}
+
+// createUpdatedAtTrigger keeps updated_at current on every UPDATE, including
+// edits made directly in SQL, so catalog watchers can see what changed
+func (c *Catalog) createUpdatedAtTrigger() error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION stores_touch_updated_at() RETURNS TRIGGER AS $$
+		BEGIN
+			NEW.updated_at = NOW();
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS stores_touch_updated_at ON stores`,
+		`CREATE TRIGGER stores_touch_updated_at BEFORE UPDATE ON stores
+		FOR EACH ROW EXECUTE FUNCTION stores_touch_updated_at()`,
+	}
+
+	for _, statement := range statements {
+		if _, err := c.db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countStores returns the number of stores
+func (c *Catalog) countStores() (int, error) {
+	var count int
+	err := c.db.QueryRow("SELECT COUNT(*) FROM stores").Scan(&count)
+	return count, err
+}
+
+// AddStore inserts a store into the catalog
+func (c *Catalog) AddStore(store *Store) error {
+	query := `
+	INSERT INTO stores (slug, name, url, description, categories, parent_company, founded_year,
+		hq_city, payment_methods, delivery_regions, cod_available, return_policy)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+	_, err := c.db.Exec(query,
+		store.Slug, store.Name, store.URL, store.Description, pq.Array(store.Categories),
+		store.ParentCompany, store.FoundedYear, store.HQCity, pq.Array(store.PaymentMethods),
+		pq.Array(store.DeliveryRegions), store.CODAvailable, store.ReturnPolicy)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return errors.New("store already exists")
+		}
+		return err
+	}
+
+	log.Printf("Store added: %s (%s)", store.Slug, store.Name)
+	return nil
+}
+
+const selectColumns = `slug, name, url, description, categories, parent_company, founded_year,
+	hq_city, payment_methods, delivery_regions, cod_available, return_policy, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanStore(row rowScanner) (*Store, error) {
+	var store Store
+	err := row.Scan(&store.Slug, &store.Name, &store.URL, &store.Description,
+		pq.Array(&store.Categories), &store.ParentCompany, &store.FoundedYear, &store.HQCity,
+		pq.Array(&store.PaymentMethods), pq.Array(&store.DeliveryRegions), &store.CODAvailable,
+		&store.ReturnPolicy, &store.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &store, nil
+}
+
+// ListStores returns every store ordered by name
+func (c *Catalog) ListStores() ([]*Store, error) {
+	rows, err := c.db.Query(`SELECT ` + selectColumns + ` FROM stores ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stores []*Store
+	for rows.Next() {
+		store, err := scanStore(rows)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, store)
+	}
+
+	return stores, rows.Err()
+}
+
+// GetStore retrieves a store by slug
+func (c *Catalog) GetStore(slug string) (*Store, bool) {
+	store, err := scanStore(c.db.QueryRow(`SELECT `+selectColumns+` FROM stores WHERE slug = $1`, slug))
+	if err == sql.ErrNoRows {
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error fetching store: %v", err)
+		return nil, false
+	}
+
+	return store, true
+}
+
+// Versions returns the last update time of every store, keyed by slug
+func (c *Catalog) Versions() (map[string]time.Time, error) {
+	rows, err := c.db.Query(`SELECT slug, updated_at FROM stores`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[string]time.Time)
+	for rows.Next() {
+		var slug string
+		var updatedAt time.Time
+		if err := rows.Scan(&slug, &updatedAt); err != nil {
+			return nil, err
+		}
+		versions[slug] = updatedAt
+	}
+
+	return versions, rows.Err()
+}
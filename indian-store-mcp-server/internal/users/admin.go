@@ -0,0 +1,70 @@
+package users
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// userInfo is what the admin API shows of a user (never the password hash)
+type userInfo struct {
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AdminHandler serves /admin/users?email=...: GET shows the user, PATCH
+// with {"role": "..."} changes their role
+func AdminHandler(store *UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := r.URL.Query().Get("email")
+		if email == "" {
+			http.Error(w, "email is required", http.StatusBadRequest)
+			return
+		}
+
+		user, ok := store.GetUser(email)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+
+		case http.MethodPatch:
+			var req struct {
+				Role string `json:"role"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Role == "" {
+				http.Error(w, `body must be {"role": "..."}`, http.StatusBadRequest)
+				return
+			}
+			if len(req.Role) > 64 {
+				http.Error(w, "role is too long", http.StatusBadRequest)
+				return
+			}
+			if err := store.SetRole(user.Email, req.Role); err != nil {
+				log.Printf("Failed to set role of %s: %v", user.Email, err)
+				http.Error(w, "Internal error", http.StatusInternalServerError)
+				return
+			}
+			user.Role = req.Role
+
+		default:
+			w.Header().Set("Allow", "GET, PATCH")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(userInfo{
+			Email:     user.Email,
+			Name:      user.Name,
+			Role:      user.Role,
+			CreatedAt: user.CreatedAt,
+		})
+	}
+}
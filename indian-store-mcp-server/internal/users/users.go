@@ -6,15 +6,25 @@ import (
 	"log"
 	"time"
 
-	_ "github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrInvalidCredentials is returned for an unknown email or a wrong password
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// dummyHash is compared against when the email is unknown, so a failed
+// login takes as long whether or not the account exists
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+
+// DefaultRole is the role of users created without one
+const DefaultRole = "user"
+
 // User represents a user in the system
 type User struct {
 	Email        string
 	PasswordHash string
 	Name         string
+	Role         string // e.g. "user" or "admin"; MFA_REQUIRED_ROLES keys off it
 	CreatedAt    time.Time
 }
 
@@ -23,17 +33,8 @@ type UserStore struct {
 	db *sql.DB
 }
 
-// NewUserStore creates a new user store with database connection
-func NewUserStore(databaseURL string) (*UserStore, error) {
-	db, err := sql.Open("postgres", databaseURL)
-	if err != nil {
-		return nil, err
-	}
-
-	if err := db.Ping(); err != nil {
-		return nil, err
-	}
-
+// NewUserStore creates a new user store on the shared database connection
+func NewUserStore(db *sql.DB) (*UserStore, error) {
 	store := &UserStore{db: db}
 
 	// Create table if not exists
@@ -50,6 +51,8 @@ func NewUserStore(databaseURL string) (*UserStore, error) {
 		log.Println("No users found, creating default admin user")
 		if err := store.AddUser("admin@indian-store.com", "admin123", "Admin User"); err != nil {
 			log.Printf("Warning: Failed to create default admin user: %v", err)
+		} else if err := store.SetRole("admin@indian-store.com", "admin"); err != nil {
+			log.Printf("Warning: Failed to make default user an admin: %v", err)
 		}
 	}
 
@@ -64,7 +67,8 @@ func (s *UserStore) createTable() error {
 		password_hash VARCHAR(255) NOT NULL,
 		name VARCHAR(255) NOT NULL,
 		created_at TIMESTAMP NOT NULL DEFAULT NOW()
-	)`
+	);
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(64) NOT NULL DEFAULT 'user'`
 
 	_, err := s.db.Exec(query)
 	return err
@@ -98,14 +102,16 @@ func (s *UserStore) AddUser(email, password, name string) error {
 	return nil
 }
 
-// Authenticate verifies email and password
+// Authenticate verifies email and password. Unknown emails still pay for a
+// bcrypt comparison so response times do not reveal which accounts exist.
 func (s *UserStore) Authenticate(email, password string) (*User, error) {
-	query := `SELECT email, password_hash, name, created_at FROM users WHERE email = $1`
+	query := `SELECT email, password_hash, name, role, created_at FROM users WHERE email = $1`
 	
 	var user User
-	err := s.db.QueryRow(query, email).Scan(&user.Email, &user.PasswordHash, &user.Name, &user.CreatedAt)
+	err := s.db.QueryRow(query, email).Scan(&user.Email, &user.PasswordHash, &user.Name, &user.Role, &user.CreatedAt)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("invalid credentials")
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
 	}
 	if err != nil {
 		return nil, err
@@ -113,7 +119,7 @@ func (s *UserStore) Authenticate(email, password string) (*User, error) {
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
 	}
 
 	return &user, nil
@@ -121,10 +127,10 @@ func (s *UserStore) Authenticate(email, password string) (*User, error) {
 
 // GetUser retrieves a user by email
 func (s *UserStore) GetUser(email string) (*User, bool) {
-	query := `SELECT email, password_hash, name, created_at FROM users WHERE email = $1`
+	query := `SELECT email, password_hash, name, role, created_at FROM users WHERE email = $1`
 	
 	var user User
-	err := s.db.QueryRow(query, email).Scan(&user.Email, &user.PasswordHash, &user.Name, &user.CreatedAt)
+	err := s.db.QueryRow(query, email).Scan(&user.Email, &user.PasswordHash, &user.Name, &user.Role, &user.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, false
 	}
@@ -138,7 +144,7 @@ func (s *UserStore) GetUser(email string) (*User, bool) {
 
 // ListUsers returns all users (without password hashes)
 func (s *UserStore) ListUsers() ([]*User, error) {
-	query := `SELECT email, name, created_at FROM users ORDER BY created_at DESC`
+	query := `SELECT email, name, role, created_at FROM users ORDER BY created_at DESC`
 	
 	rows, err := s.db.Query(query)
 	if err != nil {
@@ -149,7 +155,7 @@ func (s *UserStore) ListUsers() ([]*User, error) {
 	var users []*User
 	for rows.Next() {
 		var user User
-		if err := rows.Scan(&user.Email, &user.Name, &user.CreatedAt); err != nil {
+		if err := rows.Scan(&user.Email, &user.Name, &user.Role, &user.CreatedAt); err != nil {
 			return nil, err
 		}
 		users = append(users, &user)
@@ -158,6 +164,25 @@ func (s *UserStore) ListUsers() ([]*User, error) {
 	return users, nil
 }
 
+// SetRole changes a user's role
+func (s *UserStore) SetRole(email, role string) error {
+	result, err := s.db.Exec(`UPDATE users SET role = $2 WHERE email = $1`, email, role)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("user not found")
+	}
+
+	log.Printf("User %s now has role %s", email, role)
+	return nil
+}
+
 // DeleteUser removes a user
 func (s *UserStore) DeleteUser(email string) error {
 	query := `DELETE FROM users WHERE email = $1`
@@ -177,8 +202,3 @@ func (s *UserStore) DeleteUser(email string) error {
 	log.Printf("User deleted: %s", email)
 	return nil
 }
-
-// Close closes the database connection
-func (s *UserStore) Close() error {
-	return s.db.Close()
-}
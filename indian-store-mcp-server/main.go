@@ -1,14 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"io"
 	"log"
 	"net/http"
-	"sync"
+	"time"
 
 	"indian-store-mcp-server/internal/config"
+	"indian-store-mcp-server/internal/database"
+	"indian-store-mcp-server/internal/lockout"
+	"indian-store-mcp-server/internal/mfa"
 	"indian-store-mcp-server/internal/middleware"
 	"indian-store-mcp-server/internal/oauth"
+	"indian-store-mcp-server/internal/sessions"
+	"indian-store-mcp-server/internal/stores"
 	"indian-store-mcp-server/internal/users"
 )
 
@@ -58,6 +66,8 @@ type InitializeResult struct {
 
 type ServerCapabilities struct {
 	Tools        *ToolsCapability       `json:"tools,omitempty"`
+	Resources    *ResourcesCapability   `json:"resources,omitempty"`
+	Prompts      *PromptsCapability     `json:"prompts,omitempty"`
 	Experimental map[string]interface{} `json:"experimental,omitempty"`
 }
 
@@ -77,70 +87,139 @@ type Tool struct {
 }
 
 type InputSchema struct {
-	Type       string              `json:"type"`
-	Properties map[string]Property `json:"properties,omitempty"`
-	Required   []string            `json:"required,omitempty"`
+	Type                 string              `json:"type"`
+	Properties           map[string]Property `json:"properties,omitempty"`
+	Required             []string            `json:"required,omitempty"`
+	AdditionalProperties *bool               `json:"additionalProperties,omitempty"`
 }
 
+// Property is the subset of JSON Schema used to describe tool arguments
 type Property struct {
-	Type        string   `json:"type"`
-	Description string   `json:"description,omitempty"`
-	Enum        []string `json:"enum,omitempty"`
+	Type                 string              `json:"type,omitempty"`
+	Description          string              `json:"description,omitempty"`
+	Enum                 []string            `json:"enum,omitempty"`
+	Default              interface{}         `json:"default,omitempty"`
+	Items                *Property           `json:"items,omitempty"`
+	Properties           map[string]Property `json:"properties,omitempty"`
+	Required             []string            `json:"required,omitempty"`
+	AdditionalProperties *bool               `json:"additionalProperties,omitempty"`
+	Minimum              *float64            `json:"minimum,omitempty"`
+	Maximum              *float64            `json:"maximum,omitempty"`
+	MinLength            *int                `json:"minLength,omitempty"`
+	MaxLength            *int                `json:"maxLength,omitempty"`
+	MinItems             *int                `json:"minItems,omitempty"`
+	MaxItems             *int                `json:"maxItems,omitempty"`
+	Pattern              string              `json:"pattern,omitempty"`
+}
+
+type ToolsListParams struct {
+	Cursor string `json:"cursor,omitempty"`
 }
 
 type ToolsListResult struct {
-	Tools []Tool `json:"tools"`
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 type CallToolParams struct {
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Meta      *RequestMeta           `json:"_meta,omitempty"`
 }
 
 type CallToolResult struct {
-	Content []Content `json:"content"`
-	IsError bool      `json:"isError,omitempty"`
+	Content           []Content   `json:"content"`
+	StructuredContent interface{} `json:"structuredContent,omitempty"`
+	IsError           bool        `json:"isError,omitempty"`
 }
 
 type Content struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type     string            `json:"type"`
+	Text     string            `json:"text,omitempty"`
+	Resource *ResourceContents `json:"resource,omitempty"` // set when Type is "resource"
 }
 
+// Protocol revisions this server speaks, newest first
+var supportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// codeInsufficientScope is returned when the access token lacks a scope
+// the tool or method requires
+const codeInsufficientScope = -32003
+
+// catalogScopes are required to read store data through resources and
+// prompts, as they are for the store tools
+var catalogScopes = []string{oauth.ScopeStoresRead}
+
 type MCPServer struct {
-	initialized bool
-	mu          sync.RWMutex
+	sessions *SessionManager
+	tools    *ToolRegistry
+	catalog  *stores.Catalog
 }
 
-func NewMCPServer() *MCPServer {
-	return &MCPServer{}
+func NewMCPServer(cfg *config.Config, tools *ToolRegistry, catalog *stores.Catalog) *MCPServer {
+	sessions := NewSessionManager(time.Duration(cfg.MCPSessionIdleTimeout) * time.Second)
+	go sessions.RunJanitor(time.Minute)
+
+	s := &MCPServer{
+		sessions: sessions,
+		tools:    tools,
+		catalog:  catalog,
+	}
+
+	// Tell connected clients to re-fetch tools/list whenever it changes
+	tools.OnChange(func() {
+		s.broadcast("notifications/tools/list_changed", nil)
+	})
+
+	// Push resource updates to subscribers when catalog data changes
+	go s.watchCatalog(time.Duration(cfg.CatalogPollInterval) * time.Second)
+
+	return s
 }
 
-func (s *MCPServer) handleRequest(req JSONRPCRequest) JSONRPCResponse {
+func (s *MCPServer) handleRequest(ctx context.Context, req JSONRPCRequest) JSONRPCResponse {
 	log.Printf("Received request: method=%s id=%v", req.Method, req.ID)
 
 	switch req.Method {
 	case "initialize":
-		return s.handleInitialize(req.ID, req.Params)
+		return s.handleInitialize(ctx, req.ID, req.Params)
 	case "notifications/initialized":
+		if session := sessionFromContext(ctx); session != nil {
+			s.sessions.MarkInitialized(session.ID)
+		}
 		log.Println("Client initialized notification received")
 		return JSONRPCResponse{} // No response for notifications
 	case "tools/list":
-		s.mu.RLock()
-		initialized := s.initialized
-		s.mu.RUnlock()
-		if !initialized {
+		if sessionFromContext(ctx) == nil {
 			return s.sendError(req.ID, -32002, "Server not initialized", nil)
 		}
-		return s.handleToolsList(req.ID)
+		return s.handleToolsList(ctx, req.ID, req.Params)
 	case "tools/call":
-		s.mu.RLock()
-		initialized := s.initialized
-		s.mu.RUnlock()
-		if !initialized {
+		if sessionFromContext(ctx) == nil {
+			return s.sendError(req.ID, -32002, "Server not initialized", nil)
+		}
+		return s.handleCallTool(ctx, req.ID, req.Params)
+	case "resources/list", "resources/read", "resources/templates/list", "resources/subscribe", "resources/unsubscribe":
+		if sessionFromContext(ctx) == nil {
+			return s.sendError(req.ID, -32002, "Server not initialized", nil)
+		}
+		if req.Method == "resources/read" || req.Method == "resources/subscribe" {
+			if denied, ok := s.requireScopes(ctx, req, catalogScopes); !ok {
+				return denied
+			}
+		}
+		return s.handleResourceRequest(ctx, req)
+	case "prompts/list", "prompts/get":
+		if sessionFromContext(ctx) == nil {
 			return s.sendError(req.ID, -32002, "Server not initialized", nil)
 		}
-		return s.handleCallTool(req.ID, req.Params)
+		if req.Method == "prompts/list" {
+			return s.handlePromptsList(req.ID, req.Params)
+		}
+		if denied, ok := s.requireScopes(ctx, req, catalogScopes); !ok {
+			return denied
+		}
+		return s.handlePromptsGet(req.ID, req.Params)
 	case "ping":
 		return JSONRPCResponse{
 			JsonRPC: "2.0",
@@ -152,6 +231,22 @@ func (s *MCPServer) handleRequest(req JSONRPCRequest) JSONRPCResponse {
 	}
 }
 
+// requireScopes refuses a request whose access token lacks any of required;
+// ok is false when the returned error must be sent instead
+func (s *MCPServer) requireScopes(ctx context.Context, req JSONRPCRequest, required []string) (JSONRPCResponse, bool) {
+	principal := middleware.PrincipalFromContext(ctx)
+	missing := principal.MissingScopes(required)
+	if len(missing) == 0 {
+		return JSONRPCResponse{}, true
+	}
+	log.Printf("%s denied for %s: missing scopes %v", req.Method, principal.Subject, missing)
+	return s.sendError(req.ID, codeInsufficientScope, "Insufficient scope", map[string]interface{}{
+		"method":          req.Method,
+		"required_scopes": required,
+		"missing_scopes":  missing,
+	}), false
+}
+
 func (s *MCPServer) sendError(id interface{}, code int, message string, data interface{}) JSONRPCResponse {
 	return JSONRPCResponse{
 		JsonRPC: "2.0",
@@ -164,7 +259,7 @@ func (s *MCPServer) sendError(id interface{}, code int, message string, data int
 	}
 }
 
-func (s *MCPServer) handleInitialize(id interface{}, params json.RawMessage) JSONRPCResponse {
+func (s *MCPServer) handleInitialize(ctx context.Context, id interface{}, params json.RawMessage) JSONRPCResponse {
 	var initParams InitializeParams
 	if err := json.Unmarshal(params, &initParams); err != nil {
 		return s.sendError(id, -32602, "Invalid params", err.Error())
@@ -172,12 +267,23 @@ func (s *MCPServer) handleInitialize(id interface{}, params json.RawMessage) JSO
 
 	log.Printf("Initialize request from client: %s %s", initParams.ClientInfo.Name, initParams.ClientInfo.Version)
 
+	// Echo the client's revision when we support it, otherwise offer our latest
+	protocolVersion := supportedProtocolVersions[0]
+	if isSupportedProtocolVersion(initParams.ProtocolVersion) {
+		protocolVersion = initParams.ProtocolVersion
+	}
+
 	result := InitializeResult{
-		ProtocolVersion: "2024-11-05",  // Match the mcp-service version
+		ProtocolVersion: protocolVersion,
 		Capabilities: ServerCapabilities{
 			Tools: &ToolsCapability{
-				ListChanged: false, // No dynamic tool list changes
+				ListChanged: true, // Tools can be added or removed at runtime
 			},
+			Resources: &ResourcesCapability{
+				Subscribe:   true,
+				ListChanged: true,
+			},
+			Prompts: &PromptsCapability{},
 		},
 		ServerInfo: ServerInfo{
 			Name:    "indian-store-mcp-server",
@@ -185,9 +291,9 @@ func (s *MCPServer) handleInitialize(id interface{}, params json.RawMessage) JSO
 		},
 	}
 
-	s.mu.Lock()
-	s.initialized = true
-	s.mu.Unlock()
+	// Each initialize starts a new session bound to the caller's identity
+	session := s.sessions.Create(middleware.SubjectFromContext(ctx), initParams, protocolVersion)
+	scopeFromContext(ctx).createdID = session.ID
 
 	return JSONRPCResponse{
 		JsonRPC: "2.0",
@@ -196,19 +302,27 @@ func (s *MCPServer) handleInitialize(id interface{}, params json.RawMessage) JSO
 	}
 }
 
-func (s *MCPServer) handleToolsList(id interface{}) JSONRPCResponse {
-	tools := []Tool{
-		{
-			Name:        "list_indian_stores",
-			Description: "List popular Indian online stores with their services",
-			InputSchema: InputSchema{
-				Type:       "object",
-				Properties: map[string]Property{},
-			},
-		},
+func (s *MCPServer) handleToolsList(ctx context.Context, id interface{}, params json.RawMessage) JSONRPCResponse {
+	var listParams ToolsListParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &listParams); err != nil {
+			return s.sendError(id, -32602, "Invalid params", err.Error())
+		}
+	}
+
+	// Only list tools the caller's token is allowed to call
+	principal := middleware.PrincipalFromContext(ctx)
+	tools, nextCursor, err := s.tools.List(listParams.Cursor, func(tool ToolHandler) bool {
+		return len(principal.MissingScopes(tool.Scopes())) == 0
+	})
+	if err != nil {
+		return s.sendError(id, -32602, "Invalid params", err.Error())
 	}
 
-	result := ToolsListResult{Tools: tools}
+	result := ToolsListResult{
+		Tools:      tools,
+		NextCursor: nextCursor,
+	}
 
 	return JSONRPCResponse{
 		JsonRPC: "2.0",
@@ -217,7 +331,7 @@ func (s *MCPServer) handleToolsList(id interface{}) JSONRPCResponse {
 	}
 }
 
-func (s *MCPServer) handleCallTool(id interface{}, params json.RawMessage) JSONRPCResponse {
+func (s *MCPServer) handleCallTool(ctx context.Context, id interface{}, params json.RawMessage) JSONRPCResponse {
 	var callParams CallToolParams
 	if err := json.Unmarshal(params, &callParams); err != nil {
 		return s.sendError(id, -32602, "Invalid params", err.Error())
@@ -225,70 +339,177 @@ func (s *MCPServer) handleCallTool(id interface{}, params json.RawMessage) JSONR
 
 	log.Printf("Tool call: %s with args: %v", callParams.Name, callParams.Arguments)
 
-	switch callParams.Name {
-	case "list_indian_stores":
-		return JSONRPCResponse{
-			JsonRPC: "2.0",
-			ID:      id,
-			Result: CallToolResult{
-				Content: []Content{
-					{
-						Type: "text",
-						Text: "1. Flipkart - E-commerce platform offering electronics, fashion, home essentials\n2. Amazon India - Global e-commerce platform with wide product range\n3. Reliance Digital - Electronics and appliances retailer\n4. Myntra - Fashion and lifestyle e-commerce platform\n5. Snapdeal - E-commerce platform with various product categories\n6. Tata CLiQ - Digital commerce platform by Tata Group",
-					},
-				},
-			},
-		}
-	default:
+	tool, ok := s.tools.Get(callParams.Name)
+	if !ok {
 		return s.sendError(id, -32601, "Unknown tool", callParams.Name)
 	}
+
+	principal := middleware.PrincipalFromContext(ctx)
+	if missing := principal.MissingScopes(tool.Scopes()); len(missing) > 0 {
+		log.Printf("Tool %s denied for %s: missing scopes %v", callParams.Name, principal.Subject, missing)
+		return s.sendError(id, codeInsufficientScope, "Insufficient scope", map[string]interface{}{
+			"tool":            callParams.Name,
+			"required_scopes": tool.Scopes(),
+			"missing_scopes":  missing,
+		})
+	}
+
+	args, fieldErrs := validateArguments(tool.InputSchema(), callParams.Arguments)
+	if len(fieldErrs) > 0 {
+		return s.sendError(id, -32602, "Invalid params", map[string]interface{}{
+			"tool":   callParams.Name,
+			"errors": fieldErrs,
+		})
+	}
+
+	result, err := tool.Call(withRequestMeta(ctx, callParams.Meta), args)
+	if err != nil {
+		// Tool failures are reported in the result so the model can see them
+		log.Printf("Tool %s failed: %v", callParams.Name, err)
+		result = &CallToolResult{
+			Content: []Content{{Type: "text", Text: err.Error()}},
+			IsError: true,
+		}
+	}
+
+	return JSONRPCResponse{
+		JsonRPC: "2.0",
+		ID:      id,
+		Result:  result,
+	}
 }
 
-// HTTP handler for MCP requests
+// HTTP handler for MCP requests (Streamable HTTP transport)
 func (s *MCPServer) handleMCPRequest(w http.ResponseWriter, r *http.Request) {
 	// Set appropriate headers for MCP communication
-	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, GET, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Mcp-Session-Id, Mcp-Protocol-Version, Last-Event-ID")
+	w.Header().Set("Access-Control-Expose-Headers", "Mcp-Session-Id, WWW-Authenticate")
 
-	// Handle preflight requests
-	if r.Method == "OPTIONS" {
+	switch r.Method {
+	case http.MethodOptions:
+		// Handle preflight requests
 		w.WriteHeader(http.StatusOK)
 		return
+	case http.MethodGet:
+		s.handleMCPStream(w, r)
+		return
+	case http.MethodDelete:
+		s.handleMCPDelete(w, r)
+		return
+	case http.MethodPost:
+		// handled below
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE, OPTIONS")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	if r.Method != http.MethodPost {
-		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+	if v := r.Header.Get(headerProtocolVersion); v != "" && !isSupportedProtocolVersion(v) {
+		http.Error(w, "Unsupported "+headerProtocolVersion+": "+v, http.StatusBadRequest)
 		return
 	}
 
-	var req JSONRPCRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodySize))
 	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	// A JSON array is a JSON-RPC batch
+	if trimmed := bytes.TrimLeft(body, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+		s.handleBatch(w, r, trimmed)
+		return
+	}
+
+	var req JSONRPCRequest
+	if err := json.Unmarshal(body, &req); err != nil {
 		log.Printf("Invalid JSON-RPC request: %v", err)
+		writeParseError(w)
+		return
+	}
 
-		// Send back a parse error response
-		errorResponse := JSONRPCResponse{
-			JsonRPC: "2.0",
-			ID:      nil, // Parse errors typically have ID as null
-			Error: &RPCError{
-				Code:    -32700, // Parse error
-				Message: "Parse error: Invalid JSON",
-			},
-		}
+	scope, ok := s.resolveScope(w, r, req.Method != "initialize" && req.Method != "ping")
+	if !ok {
+		return
+	}
 
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(errorResponse)
+	// Notifications and client responses get no JSON-RPC reply
+	if isNotification(req) {
+		s.handleRequest(withScope(r.Context(), scope), req)
+		w.WriteHeader(http.StatusAccepted)
 		return
 	}
 
+	stream := newResponseStream(w, r)
+	scope.notify = stream.notify
+
 	// Process the request
-	response := s.handleRequest(req)
+	response := s.handleRequest(withScope(r.Context(), scope), req)
+
+	if scope.createdID != "" {
+		w.Header().Set(headerSessionID, scope.createdID)
+	}
+
+	// A request refused for missing scopes is also an HTTP 403 challenge so
+	// the client can step up authorization for the scopes it lacks
+	if response.Error != nil && response.Error.Code == codeInsufficientScope {
+		data, _ := response.Error.Data.(map[string]interface{})
+		missing, _ := data["missing_scopes"].([]string)
+		w.Header().Set("WWW-Authenticate", oauth.BearerChallenge(r, oauth.ErrInsufficientScope,
+			"The access token lacks scopes required by this request", missing))
+		stream.finishWithStatus(response, http.StatusForbidden)
+		return
+	}
 
 	// Send response
+	stream.finish(response)
+}
+
+// resolveScope looks up the session named by Mcp-Session-Id. Everything
+// except initialize (and ping) runs inside a session. An ID we never issued,
+// that expired, or that belongs to another user gets 404 so the client knows
+// to start over with a fresh initialize. ok is false once an HTTP error has
+// been written.
+func (s *MCPServer) resolveScope(w http.ResponseWriter, r *http.Request, needsSession bool) (*requestScope, bool) {
+	scope := &requestScope{}
+
+	if sessionID := r.Header.Get(headerSessionID); sessionID != "" {
+		session, err := s.sessions.Get(sessionID, middleware.SubjectFromContext(r.Context()))
+		if err != nil {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return nil, false
+		}
+		scope.session = session
+	} else if needsSession {
+		http.Error(w, "Missing "+headerSessionID+" header", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return scope, true
+}
+
+// isNotification reports whether msg expects no reply: notifications have no
+// ID, and responses to server requests have no method
+func isNotification(msg JSONRPCRequest) bool {
+	return msg.ID == nil || msg.Method == ""
+}
+
+func writeParseError(w http.ResponseWriter) {
+	// Send back a parse error response
+	errorResponse := JSONRPCResponse{
+		JsonRPC: "2.0",
+		ID:      nil, // Parse errors typically have ID as null
+		Error: &RPCError{
+			Code:    -32700, // Parse error
+			Message: "Parse error: Invalid JSON",
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(errorResponse)
 }
 
 func healthCheck(w http.ResponseWriter, r *http.Request) {
@@ -300,46 +521,6 @@ func healthCheck(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// OAuth Discovery endpoint for MCP clients
-func oauthDiscovery(cfg *config.Config) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Determine the base URL from headers or construct from request
-		scheme := "https"
-		if r.TLS == nil && r.Header.Get("X-Forwarded-Proto") == "" {
-			scheme = "http"
-		}
-		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
-			scheme = proto
-		}
-		
-		host := r.Host
-		if forwardedHost := r.Header.Get("X-Forwarded-Host"); forwardedHost != "" {
-			host = forwardedHost
-		}
-		
-		baseURL := scheme + "://" + host
-
-		// OAuth discovery - clients use Ory Hydra directly, we only provide registration
-		discovery := map[string]interface{}{
-			"issuer":                                baseURL,
-			"authorization_endpoint":                baseURL + "/oauth2/auth",
-			"token_endpoint":                        baseURL + "/oauth2/token",
-			"registration_endpoint":                 baseURL + "/oauth/register",
-			"userinfo_endpoint":                     baseURL + "/oauth2/userinfo",
-			"introspection_endpoint":                baseURL + "/oauth2/introspect",
-			"response_types_supported":              []string{"code"},
-			"grant_types_supported":                 []string{"authorization_code", "refresh_token"},
-			"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
-			"scopes_supported":                      []string{"openid", "offline_access", "email", "profile"},
-			"subject_types_supported":               []string{"public"},
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(discovery)
-	}
-}
-
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
@@ -351,30 +532,131 @@ func main() {
 	oryClient := oauth.NewOryClient(cfg)
 	log.Printf("Ory client initialized with URL: %s", cfg.OryURL)
 
+	// Connect to PostgreSQL (shared by the user store and store catalog)
+	db, err := database.Open(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
 	// Initialize user store with database
-	userStore, err := users.NewUserStore(cfg.DatabaseURL)
+	userStore, err := users.NewUserStore(db)
 	if err != nil {
 		log.Fatalf("Failed to initialize user store: %v", err)
 	}
 	log.Println("User store initialized with database connection")
 
+	// Initialize the store catalog with database
+	catalog, err := stores.NewCatalog(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize store catalog: %v", err)
+	}
+	log.Println("Store catalog initialized with database connection")
+
+	// Registration access tokens for RFC 7592 client management
+	registrationStore, err := oauth.NewRegistrationStore(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize registration store: %v", err)
+	}
+
+	// Initial access tokens gate registration when REGISTRATION_REQUIRE_INITIAL_TOKEN is set
+	initialTokenStore, err := oauth.NewInitialAccessTokenStore(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize initial access token store: %v", err)
+	}
+
 	// Create registration handler for dynamic client registration
-	registrationHandler := oauth.NewRegistrationHandler(cfg, oryClient)
+	registrationHandler := oauth.NewRegistrationHandler(cfg, oryClient, registrationStore, initialTokenStore)
+	
+	// Reaper for clients that stopped getting tokens (fed by Hydra's token hook)
+	clientReaper := oauth.NewClientReaper(cfg, oryClient, registrationStore)
+	if cfg.ClientReaperEnabled {
+		log.Printf("Client reaper enabled (idle window %ds, dry run: %v)", cfg.ClientReaperMaxIdle, cfg.ClientReaperDryRun)
+		go clientReaper.RunPeriodically(time.Duration(cfg.ClientReaperInterval) * time.Second)
+	}
 	
+	// Login sessions for the login, consent and account pages
+	var sessionStore sessions.SessionStore
+	sessionLifetime := time.Duration(cfg.SessionLifetime) * time.Second
+	if cfg.SessionStore == "memory" {
+		log.Println("Warning: login sessions are kept in memory and are not shared between replicas")
+		sessionStore = sessions.NewMemoryStore(sessionLifetime)
+	} else {
+		sessionStore, err = sessions.NewPostgresStore(db, sessionLifetime)
+		if err != nil {
+			log.Fatalf("Failed to initialize session store: %v", err)
+		}
+	}
+	go sessions.RunJanitor(sessionStore, time.Duration(cfg.SessionCleanupInterval)*time.Second)
+
+	// Failed login tracking and lockout
+	lockoutStore, err := lockout.NewStore(db, lockout.Policy{
+		MaxFailures:   cfg.LoginMaxFailures,
+		IPMaxFailures: cfg.LoginIPMaxFailures,
+		Window:        time.Duration(cfg.LoginFailureWindow) * time.Second,
+		Base:          time.Duration(cfg.LoginLockoutBase) * time.Second,
+		Max:           time.Duration(cfg.LoginLockoutMax) * time.Second,
+		LogRetention:  time.Duration(cfg.SecurityLogRetention) * time.Second,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize login lockout store: %v", err)
+	}
+	go lockout.RunJanitor(lockoutStore, time.Hour)
+
+	// TOTP two-factor authentication
+	mfaStore, err := mfa.NewStore(db, cfg.MFAIssuer)
+	if err != nil {
+		log.Fatalf("Failed to initialize MFA store: %v", err)
+	}
+	go mfa.RunJanitor(mfaStore, 10*time.Minute)
+	if len(cfg.MFARequiredRoles) > 0 {
+		log.Printf("Two-factor authentication required for roles: %v", cfg.MFARequiredRoles)
+	}
+
 	// Create login/consent handler for Ory Hydra flows
-	loginConsentHandler := oauth.NewLoginConsentHandler(oryClient, userStore)
+	loginConsentHandler := oauth.NewLoginConsentHandler(cfg, oryClient, userStore, sessionStore, lockoutStore, mfaStore)
 
 	// Create authentication middleware
-	authMiddleware := middleware.NewAuthMiddleware(oryClient)
+	tokenValidator := oauth.NewTokenValidator(cfg, oryClient)
+	authMiddleware := middleware.NewAuthMiddleware(tokenValidator)
+
+	// Account pages (connected apps, 2FA), signed in through the same session
+	accountHandler := oauth.NewAccountHandler(cfg, oryClient, tokenValidator, sessionStore, userStore, mfaStore, lockoutStore)
+
+	// Register MCP tools
+	tools := NewToolRegistry()
+	for _, tool := range []ToolHandler{
+		&listIndianStoresTool{catalog: catalog},
+		&searchStoresTool{catalog: catalog},
+	} {
+		if err := tools.Register(tool); err != nil {
+			log.Fatalf("Failed to register tool: %v", err)
+		}
+	}
 
 	// Create MCP server
-	server := NewMCPServer()
+	server := NewMCPServer(cfg, tools, catalog)
 
-	// OAuth discovery endpoint (required by MCP clients)
-	http.HandleFunc("/.well-known/oauth-authorization-server", middleware.CORS(oauthDiscovery(cfg)))
+	// OAuth/OIDC discovery (required by MCP clients), sourced from Hydra
+	discoveryHandler := oauth.NewDiscoveryHandler(cfg, oryClient)
+	http.HandleFunc("/.well-known/oauth-authorization-server", middleware.CORS(discoveryHandler.HandleDiscovery))
+	http.HandleFunc("/.well-known/oauth-authorization-server/", middleware.CORS(discoveryHandler.HandleDiscovery))
+	http.HandleFunc("/.well-known/openid-configuration", middleware.CORS(discoveryHandler.HandleDiscovery))
+
+	// Protected resource metadata (RFC 9728), referenced by WWW-Authenticate
+	http.HandleFunc("/.well-known/oauth-protected-resource", middleware.CORS(discoveryHandler.HandleProtectedResourceMetadata))
+	http.HandleFunc("/.well-known/oauth-protected-resource/mcp", middleware.CORS(discoveryHandler.HandleProtectedResourceMetadata))
 
 	// Setup OAuth registration endpoint (only endpoint we handle, rest is Ory)
 	http.HandleFunc("/oauth/register", middleware.CORS(registrationHandler.HandleRegister))
+	http.HandleFunc("/oauth/register/", middleware.CORS(registrationHandler.HandleClientConfiguration))
+
+	// Token revocation (RFC 7009), proxied to Hydra
+	revocationHandler := oauth.NewRevocationHandler(oryClient, tokenValidator)
+	http.HandleFunc("/oauth/revoke", middleware.CORS(revocationHandler.HandleRevoke))
+
+	// Hydra token hook, records when each registered client last got a token
+	http.HandleFunc("/oauth/token-hook", clientReaper.HandleTokenHook)
 	
 	// Redirect /oauth/authorize to /oauth2/auth for backward compatibility with cached clients
 	http.HandleFunc("/oauth/authorize", middleware.CORS(func(w http.ResponseWriter, r *http.Request) {
@@ -385,15 +667,37 @@ func main() {
 
 	// Hydra Login/Consent and error fallback pages
 	http.HandleFunc("/login", middleware.CORS(loginConsentHandler.HandleLogin))
+	http.HandleFunc("/login/mfa", loginConsentHandler.HandleLoginMFA)
 	http.HandleFunc("/consent", middleware.CORS(loginConsentHandler.HandleConsent))
 	http.HandleFunc("/oauth2/fallbacks/error", middleware.CORS(loginConsentHandler.HandleError))
+	http.HandleFunc("/logout", loginConsentHandler.HandleLogout)
+	http.HandleFunc("/logout/done", loginConsentHandler.HandleLoggedOut)
+
+	// Account pages for the signed-in user (same-origin only, no CORS)
+	http.HandleFunc("/account/apps", accountHandler.HandleApps)
+	http.HandleFunc("/account/mfa", accountHandler.HandleMFA)
+	http.HandleFunc("/account/api/apps", accountHandler.HandleAppsAPI)
+	http.HandleFunc("/account/api/apps/", accountHandler.HandleAppsAPI)
 
-	// Setup MCP endpoint (protected with auth)
+	// Setup MCP endpoint (protected with auth): POST for messages, GET for the SSE stream
 	http.HandleFunc("/mcp", middleware.CORS(authMiddleware.RequireAuth(server.handleMCPRequest)))
 
+	// Admin API (ADMIN_API_TOKEN; disabled when unset)
+	http.HandleFunc("/admin/registration-tokens", middleware.AdminOnly(cfg, registrationHandler.HandleInitialAccessTokens))
+	http.HandleFunc("/admin/registration-tokens/", middleware.AdminOnly(cfg, registrationHandler.HandleInitialAccessToken))
+	http.HandleFunc("/admin/clients/reap", middleware.AdminOnly(cfg, clientReaper.HandleReaper))
+	http.HandleFunc("/admin/sessions", middleware.AdminOnly(cfg, sessions.AdminHandler(sessionStore)))
+	http.HandleFunc("/admin/lockouts", middleware.AdminOnly(cfg, lockout.AdminHandler(lockoutStore)))
+	http.HandleFunc("/admin/security-log", middleware.AdminOnly(cfg, lockout.EventsHandler(lockoutStore)))
+	http.HandleFunc("/admin/users", middleware.AdminOnly(cfg, users.AdminHandler(userStore)))
+	http.HandleFunc("/admin/mfa", middleware.AdminOnly(cfg, mfa.AdminHandler(mfaStore)))
+
 	// Health check (no auth required)
 	http.HandleFunc("/health", healthCheck)
 
+	// Prometheus metrics (no auth required, counters only)
+	http.HandleFunc("/metrics", metricsHandler(tokenValidator))
+
 	// Start server
 	addr := cfg.Host + ":" + cfg.Port
 	log.Printf("Indian Store MCP Server with Ory OAuth starting on %s", addr)
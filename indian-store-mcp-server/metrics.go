@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"indian-store-mcp-server/internal/oauth"
+)
+
+// metricsHandler serves counters in the Prometheus text exposition format
+func metricsHandler(validator *oauth.TokenValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		stats, enabled := validator.IntrospectionStats()
+		if !enabled {
+			return
+		}
+		writeCounter(w, "mcp_introspection_cache_hits_total", "Token introspections answered from the cache", stats.Hits)
+		writeCounter(w, "mcp_introspection_cache_misses_total", "Token introspections not found in the cache", stats.Misses)
+		writeCounter(w, "mcp_introspection_cache_shared_total", "Cache misses that waited on another request's introspection", stats.Shared)
+		writeCounter(w, "mcp_introspection_cache_evictions_total", "Cache entries evicted to stay under the size limit", stats.Evictions)
+		fmt.Fprintf(w, "# HELP mcp_introspection_cache_entries Tokens currently cached\n")
+		fmt.Fprintf(w, "# TYPE mcp_introspection_cache_entries gauge\n")
+		fmt.Fprintf(w, "mcp_introspection_cache_entries %d\n", stats.Size)
+	}
+}
+
+func writeCounter(w http.ResponseWriter, name, help string, value uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	fmt.Fprintf(w, "%s %d\n", name, value)
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"indian-store-mcp-server/internal/stores"
+)
+
+// Curated prompt templates so every client (ChatGPT, Claude, ...) asks the
+// catalog the same way. Rendered messages embed store:// resources, giving
+// the model the catalog data up front instead of relying on tool calls.
+
+const maxPromptStores = 5
+
+type PromptsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+type PromptsListResult struct {
+	Prompts    []Prompt `json:"prompts"`
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+type GetPromptParams struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+type PromptMessage struct {
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+}
+
+type GetPromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// promptTemplate is a prompt plus the schema its arguments are validated
+// against and the function that renders it
+type promptTemplate struct {
+	name        string
+	description string
+	arguments   map[string]Property
+	required    []string
+	order       []string // argument order in prompts/list
+	render      func(catalog *stores.Catalog, args ToolArgs) ([]PromptMessage, error)
+}
+
+// promptArgumentError reports an argument that passed the schema but does
+// not make sense against the catalog (unknown store, unmapped pincode)
+type promptArgumentError struct {
+	FieldError
+}
+
+func (e *promptArgumentError) Error() string {
+	return e.Message
+}
+
+var promptTemplates = []promptTemplate{
+	{
+		name:        "compare_stores",
+		description: "Compare the stores that sell a product category",
+		arguments: map[string]Property{
+			"category": {Type: "string", Description: "Product category", Enum: stores.Categories},
+			"location": {Type: "string", Description: "Optional city or state the order ships to", MaxLength: intPtr(100)},
+		},
+		required: []string{"category"},
+		order:    []string{"category", "location"},
+		render:   renderCompareStores,
+	},
+	{
+		name:        "find_store_for_pincode",
+		description: "Find stores that deliver to a pincode with cash on delivery",
+		arguments: map[string]Property{
+			"pincode":  {Type: "string", Description: "Six-digit Indian pincode", Pattern: stores.PincodePattern},
+			"category": {Type: "string", Description: "Optional product category", Enum: stores.Categories},
+		},
+		required: []string{"pincode"},
+		order:    []string{"pincode", "category"},
+		render:   renderFindStoreForPincode,
+	},
+	{
+		name:        "explain_return_policy",
+		description: "Explain the return policy of a store in plain language",
+		arguments: map[string]Property{
+			"store": {Type: "string", Description: "Store slug, e.g. flipkart", MinLength: intPtr(1), MaxLength: intPtr(100)},
+		},
+		required: []string{"store"},
+		order:    []string{"store"},
+		render:   renderExplainReturnPolicy,
+	},
+}
+
+func findPromptTemplate(name string) (*promptTemplate, bool) {
+	for i := range promptTemplates {
+		if promptTemplates[i].name == name {
+			return &promptTemplates[i], true
+		}
+	}
+	return nil, false
+}
+
+func (s *MCPServer) handlePromptsList(id interface{}, params json.RawMessage) JSONRPCResponse {
+	var listParams ListParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &listParams); err != nil {
+			return s.sendError(id, -32602, "Invalid params", err.Error())
+		}
+	}
+
+	result := PromptsListResult{Prompts: []Prompt{}}
+	for _, tmpl := range promptTemplates {
+		prompt := Prompt{Name: tmpl.name, Description: tmpl.description}
+		for _, name := range tmpl.order {
+			prompt.Arguments = append(prompt.Arguments, PromptArgument{
+				Name:        name,
+				Description: tmpl.arguments[name].Description,
+				Required:    containsString(tmpl.required, name),
+			})
+		}
+		result.Prompts = append(result.Prompts, prompt)
+	}
+
+	return JSONRPCResponse{
+		JsonRPC: "2.0",
+		ID:      id,
+		Result:  result,
+	}
+}
+
+func (s *MCPServer) handlePromptsGet(id interface{}, params json.RawMessage) JSONRPCResponse {
+	var getParams GetPromptParams
+	if err := json.Unmarshal(params, &getParams); err != nil {
+		return s.sendError(id, -32602, "Invalid params", err.Error())
+	}
+
+	tmpl, ok := findPromptTemplate(getParams.Name)
+	if !ok {
+		return s.sendError(id, -32602, "Unknown prompt", map[string]string{"prompt": getParams.Name})
+	}
+
+	// Prompt arguments are always strings on the wire; the schema adds
+	// enums, patterns and lengths on top
+	raw := make(map[string]interface{}, len(getParams.Arguments))
+	for name, value := range getParams.Arguments {
+		raw[name] = value
+	}
+	noExtra := false
+	args, fieldErrs := validateArguments(InputSchema{
+		Type:                 "object",
+		Properties:           tmpl.arguments,
+		Required:             tmpl.required,
+		AdditionalProperties: &noExtra,
+	}, raw)
+	if len(fieldErrs) > 0 {
+		return s.sendError(id, -32602, "Invalid params", map[string]interface{}{
+			"prompt": tmpl.name,
+			"errors": fieldErrs,
+		})
+	}
+
+	messages, err := tmpl.render(s.catalog, args)
+	if err != nil {
+		var argErr *promptArgumentError
+		if errors.As(err, &argErr) {
+			return s.sendError(id, -32602, "Invalid params", map[string]interface{}{
+				"prompt": tmpl.name,
+				"errors": []FieldError{argErr.FieldError},
+			})
+		}
+		log.Printf("Failed to render prompt %s: %v", tmpl.name, err)
+		return s.sendError(id, -32603, "Internal error", nil)
+	}
+
+	return JSONRPCResponse{
+		JsonRPC: "2.0",
+		ID:      id,
+		Result: GetPromptResult{
+			Description: tmpl.description,
+			Messages:    messages,
+		},
+	}
+}
+
+func renderCompareStores(catalog *stores.Catalog, args ToolArgs) ([]PromptMessage, error) {
+	category := args.String("category")
+	location := args.String("location")
+
+	page, err := catalog.Search(stores.SearchQuery{
+		Category: category,
+		Location: location,
+		Sort:     "name",
+		PageSize: maxPromptStores,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	where := ""
+	if location != "" {
+		where = " delivering to " + location
+	}
+	if len(page.Hits) == 0 {
+		return []PromptMessage{textMessage(fmt.Sprintf(
+			"I want to buy %s online in India%s, but the catalog has no matching store. "+
+				"Suggest what I should look for in a store instead.", category, where))}, nil
+	}
+
+	messages := []PromptMessage{textMessage(fmt.Sprintf(
+		"Compare these Indian online stores for buying %s%s. Cover delivery coverage, "+
+			"payment options including cash on delivery, and return policy, then recommend "+
+			"one store and explain why.", category, where))}
+	for _, hit := range page.Hits {
+		messages = append(messages, storeResourceMessage(hit.Store))
+	}
+	return messages, nil
+}
+
+func renderFindStoreForPincode(catalog *stores.Catalog, args ToolArgs) ([]PromptMessage, error) {
+	pincode := args.String("pincode")
+	category := args.String("category")
+
+	state, ok := stores.StateForPincode(pincode)
+	if !ok {
+		return nil, &promptArgumentError{FieldError{
+			Field:   "pincode",
+			Rule:    "pincode",
+			Message: "pincode " + pincode + " does not belong to a known postal circle",
+		}}
+	}
+
+	cod := true
+	page, err := catalog.Search(stores.SearchQuery{
+		Category: category,
+		Location: state,
+		COD:      &cod,
+		Sort:     "name",
+		PageSize: maxPromptStores,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	what := "products"
+	if category != "" {
+		what = category
+	}
+	if len(page.Hits) == 0 {
+		return []PromptMessage{textMessage(fmt.Sprintf(
+			"I need %s delivered to pincode %s (%s) with cash on delivery, but the catalog "+
+				"has no store offering that. Suggest alternatives such as prepaid UPI orders.",
+			what, pincode, titleCase(state)))}, nil
+	}
+
+	messages := []PromptMessage{textMessage(fmt.Sprintf(
+		"I need %s delivered to pincode %s (%s) and want to pay cash on delivery. "+
+			"From the stores below, tell me which ones fit and anything I should check "+
+			"before ordering, such as COD limits.", what, pincode, titleCase(state)))}
+	for _, hit := range page.Hits {
+		messages = append(messages, storeResourceMessage(hit.Store))
+	}
+	return messages, nil
+}
+
+func renderExplainReturnPolicy(catalog *stores.Catalog, args ToolArgs) ([]PromptMessage, error) {
+	slug := strings.ToLower(args.String("store"))
+
+	store, ok := catalog.GetStore(slug)
+	if !ok {
+		return nil, &promptArgumentError{FieldError{
+			Field:   "store",
+			Rule:    "exists",
+			Message: "no store with slug " + slug,
+		}}
+	}
+
+	policyURI := storeURI(store.Slug) + "/policies/returns"
+	return []PromptMessage{
+		textMessage(fmt.Sprintf(
+			"Explain the return policy of %s in plain language: how long I have to return "+
+				"an item, what is excluded, and how refunds are paid.", store.Name)),
+		{
+			Role: "user",
+			Content: Content{
+				Type: "resource",
+				Resource: &ResourceContents{
+					URI:      policyURI,
+					MimeType: mimeMarkdown,
+					Text:     policyMarkdown(store, "returns"),
+				},
+			},
+		},
+		storeResourceMessage(store),
+	}, nil
+}
+
+func textMessage(text string) PromptMessage {
+	return PromptMessage{
+		Role:    "user",
+		Content: Content{Type: "text", Text: text},
+	}
+}
+
+// storeResourceMessage embeds the store:// resource of a store
+func storeResourceMessage(store *stores.Store) PromptMessage {
+	return PromptMessage{
+		Role: "user",
+		Content: Content{
+			Type: "resource",
+			Resource: &ResourceContents{
+				URI:      storeURI(store.Slug),
+				MimeType: mimeMarkdown,
+				Text:     storeMarkdown(store),
+			},
+		},
+	}
+}
+
+// titleCase capitalises each word of a lower-case state name
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		if word != "and" {
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
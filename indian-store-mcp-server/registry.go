@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+)
+
+const toolsPageSize = 50
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// ToolHandler is implemented by every tool served over tools/list and tools/call
+type ToolHandler interface {
+	Name() string
+	Description() string
+	InputSchema() InputSchema
+	Scopes() []string // OAuth scopes the caller's token must carry
+	Call(ctx context.Context, args ToolArgs) (*CallToolResult, error)
+}
+
+// ToolRegistry holds the tools this server exposes, in registration order
+type ToolRegistry struct {
+	tools    map[string]ToolHandler
+	order    []string
+	onChange func()
+	mu       sync.RWMutex
+}
+
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{
+		tools: make(map[string]ToolHandler),
+	}
+}
+
+// OnChange sets the callback run after a tool is added or removed
+func (r *ToolRegistry) OnChange(fn func()) {
+	r.mu.Lock()
+	r.onChange = fn
+	r.mu.Unlock()
+}
+
+// Register adds a tool; names must be unique
+func (r *ToolRegistry) Register(tool ToolHandler) error {
+	r.mu.Lock()
+	if _, exists := r.tools[tool.Name()]; exists {
+		r.mu.Unlock()
+		return fmt.Errorf("tool %q is already registered", tool.Name())
+	}
+	r.tools[tool.Name()] = tool
+	r.order = append(r.order, tool.Name())
+	onChange := r.onChange
+	r.mu.Unlock()
+
+	log.Printf("Tool registered: %s", tool.Name())
+	if onChange != nil {
+		onChange()
+	}
+	return nil
+}
+
+// Unregister removes a tool and reports whether it was present
+func (r *ToolRegistry) Unregister(name string) bool {
+	r.mu.Lock()
+	if _, exists := r.tools[name]; !exists {
+		r.mu.Unlock()
+		return false
+	}
+	delete(r.tools, name)
+	for i, n := range r.order {
+		if n == name {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	onChange := r.onChange
+	r.mu.Unlock()
+
+	log.Printf("Tool unregistered: %s", name)
+	if onChange != nil {
+		onChange()
+	}
+	return true
+}
+
+// Get looks up a tool by name
+func (r *ToolRegistry) Get(name string) (ToolHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// List returns one page of the tools accepted by visible, starting at
+// cursor, plus the cursor of the next page ("" on the last page)
+func (r *ToolRegistry) List(cursor string, visible func(ToolHandler) bool) ([]Tool, string, error) {
+	offset, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var names []string
+	for _, name := range r.order {
+		if visible(r.tools[name]) {
+			names = append(names, name)
+		}
+	}
+	if offset > len(names) {
+		return nil, "", errInvalidCursor
+	}
+
+	end := offset + toolsPageSize
+	if end > len(names) {
+		end = len(names)
+	}
+
+	tools := make([]Tool, 0, end-offset)
+	for _, name := range names[offset:end] {
+		handler := r.tools[name]
+		tools = append(tools, Tool{
+			Name:        handler.Name(),
+			Description: handler.Description(),
+			InputSchema: handler.InputSchema(),
+		})
+	}
+
+	nextCursor := ""
+	if end < len(names) {
+		nextCursor = encodeCursor(end)
+	}
+	return tools, nextCursor, nil
+}
+
+// Cursors are opaque to clients; internally they are a base64 offset
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, errInvalidCursor
+	}
+	return offset, nil
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"indian-store-mcp-server/internal/stores"
+)
+
+// Store profiles are exposed as MCP resources:
+//
+//	store://{slug}                  JSON and markdown profile of a store
+//	store://{slug}/policies/{kind}  one policy (returns, payments, delivery, cod)
+
+const (
+	storeURIScheme     = "store://"
+	resourcesPageSize  = 50
+	mimeJSON           = "application/json"
+	mimeMarkdown       = "text/markdown"
+	codeResourceAbsent = -32002
+)
+
+// Policy kinds served under store://{slug}/policies/{kind}
+var policyKinds = []string{"returns", "payments", "delivery", "cod"}
+
+type ResourcesCapability struct {
+	Subscribe   bool `json:"subscribe,omitempty"`
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+type ListParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type ResourcesListResult struct {
+	Resources  []Resource `json:"resources"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+type ResourceTemplatesListResult struct {
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+}
+
+type ResourceParams struct {
+	URI string `json:"uri"`
+}
+
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+type ReadResourceResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+func storeURI(slug string) string {
+	return storeURIScheme + slug
+}
+
+// parseStoreURI splits store://{slug}[/policies/{kind}]
+func parseStoreURI(uri string) (slug, kind string, ok bool) {
+	if !strings.HasPrefix(uri, storeURIScheme) {
+		return "", "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(uri, storeURIScheme), "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return parts[0], "", true
+	case len(parts) == 3 && parts[0] != "" && parts[1] == "policies" && containsString(policyKinds, parts[2]):
+		return parts[0], parts[2], true
+	default:
+		return "", "", false
+	}
+}
+
+// handleResourceRequest dispatches the resources/* methods
+func (s *MCPServer) handleResourceRequest(ctx context.Context, req JSONRPCRequest) JSONRPCResponse {
+	switch req.Method {
+	case "resources/list":
+		return s.handleResourcesList(req.ID, req.Params)
+	case "resources/templates/list":
+		return s.handleResourceTemplatesList(req.ID)
+	case "resources/read":
+		return s.handleResourcesRead(req.ID, req.Params)
+	case "resources/subscribe":
+		return s.handleResourcesSubscribe(ctx, req.ID, req.Params, true)
+	default:
+		return s.handleResourcesSubscribe(ctx, req.ID, req.Params, false)
+	}
+}
+
+func (s *MCPServer) handleResourcesList(id interface{}, params json.RawMessage) JSONRPCResponse {
+	var listParams ListParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &listParams); err != nil {
+			return s.sendError(id, -32602, "Invalid params", err.Error())
+		}
+	}
+
+	offset, err := decodeCursor(listParams.Cursor)
+	if err != nil {
+		return s.sendError(id, -32602, "Invalid params", err.Error())
+	}
+
+	all, err := s.catalog.ListStores()
+	if err != nil {
+		log.Printf("Failed to list stores: %v", err)
+		return s.sendError(id, -32603, "Internal error", nil)
+	}
+	if offset > len(all) {
+		return s.sendError(id, -32602, "Invalid params", errInvalidCursor.Error())
+	}
+
+	end := offset + resourcesPageSize
+	if end > len(all) {
+		end = len(all)
+	}
+
+	result := ResourcesListResult{Resources: []Resource{}}
+	for _, store := range all[offset:end] {
+		result.Resources = append(result.Resources, Resource{
+			URI:         storeURI(store.Slug),
+			Name:        store.Name,
+			Description: store.Description,
+			MimeType:    mimeJSON,
+		})
+	}
+	if end < len(all) {
+		result.NextCursor = encodeCursor(end)
+	}
+
+	return JSONRPCResponse{
+		JsonRPC: "2.0",
+		ID:      id,
+		Result:  result,
+	}
+}
+
+func (s *MCPServer) handleResourceTemplatesList(id interface{}) JSONRPCResponse {
+	result := ResourceTemplatesListResult{
+		ResourceTemplates: []ResourceTemplate{
+			{
+				URITemplate: "store://{slug}",
+				Name:        "Store profile",
+				Description: "Profile of an Indian online store (JSON and markdown)",
+				MimeType:    mimeJSON,
+			},
+			{
+				URITemplate: "store://{slug}/policies/{kind}",
+				Name:        "Store policy",
+				Description: "One policy of a store; kind is one of: " + strings.Join(policyKinds, ", "),
+				MimeType:    mimeMarkdown,
+			},
+		},
+	}
+
+	return JSONRPCResponse{
+		JsonRPC: "2.0",
+		ID:      id,
+		Result:  result,
+	}
+}
+
+func (s *MCPServer) handleResourcesRead(id interface{}, params json.RawMessage) JSONRPCResponse {
+	var readParams ResourceParams
+	if err := json.Unmarshal(params, &readParams); err != nil {
+		return s.sendError(id, -32602, "Invalid params", err.Error())
+	}
+
+	contents, ok := s.readStoreResource(readParams.URI)
+	if !ok {
+		return s.sendError(id, codeResourceAbsent, "Resource not found", map[string]string{"uri": readParams.URI})
+	}
+
+	return JSONRPCResponse{
+		JsonRPC: "2.0",
+		ID:      id,
+		Result:  ReadResourceResult{Contents: contents},
+	}
+}
+
+// readStoreResource renders a store:// URI in every representation it has
+func (s *MCPServer) readStoreResource(uri string) ([]ResourceContents, bool) {
+	slug, kind, ok := parseStoreURI(uri)
+	if !ok {
+		return nil, false
+	}
+	store, ok := s.catalog.GetStore(slug)
+	if !ok {
+		return nil, false
+	}
+
+	if kind != "" {
+		return []ResourceContents{
+			{URI: uri, MimeType: mimeMarkdown, Text: policyMarkdown(store, kind)},
+		}, true
+	}
+
+	profile, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		log.Printf("Failed to encode store %s: %v", slug, err)
+		return nil, false
+	}
+	return []ResourceContents{
+		{URI: uri, MimeType: mimeJSON, Text: string(profile)},
+		{URI: uri, MimeType: mimeMarkdown, Text: storeMarkdown(store)},
+	}, true
+}
+
+func (s *MCPServer) handleResourcesSubscribe(ctx context.Context, id interface{}, params json.RawMessage, subscribe bool) JSONRPCResponse {
+	var subParams ResourceParams
+	if err := json.Unmarshal(params, &subParams); err != nil {
+		return s.sendError(id, -32602, "Invalid params", err.Error())
+	}
+
+	slug, _, ok := parseStoreURI(subParams.URI)
+	if !ok {
+		return s.sendError(id, codeResourceAbsent, "Resource not found", map[string]string{"uri": subParams.URI})
+	}
+	if _, ok := s.catalog.GetStore(slug); !ok && subscribe {
+		return s.sendError(id, codeResourceAbsent, "Resource not found", map[string]string{"uri": subParams.URI})
+	}
+
+	session := sessionFromContext(ctx)
+	if subscribe {
+		s.sessions.Subscribe(session.ID, subParams.URI)
+	} else {
+		s.sessions.Unsubscribe(session.ID, subParams.URI)
+	}
+
+	return JSONRPCResponse{
+		JsonRPC: "2.0",
+		ID:      id,
+		Result:  map[string]string{},
+	}
+}
+
+// watchCatalog polls the catalog for changed stores and notifies
+// subscribers. Edits made straight in the database are picked up because
+// the stores table bumps updated_at on every UPDATE.
+func (s *MCPServer) watchCatalog(interval time.Duration) {
+	previous, err := s.catalog.Versions()
+	if err != nil {
+		log.Printf("Catalog watcher: initial snapshot failed: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		current, err := s.catalog.Versions()
+		if err != nil {
+			log.Printf("Catalog watcher: %v", err)
+			continue
+		}
+
+		listChanged := len(current) != len(previous)
+		var changed []string
+		for slug, updatedAt := range current {
+			prev, existed := previous[slug]
+			if !existed {
+				listChanged = true
+			} else if !updatedAt.Equal(prev) {
+				changed = append(changed, slug)
+			}
+		}
+		for slug := range previous {
+			if _, exists := current[slug]; !exists {
+				changed = append(changed, slug)
+			}
+		}
+		previous = current
+
+		sort.Strings(changed)
+		for _, slug := range changed {
+			uri := storeURI(slug)
+			log.Printf("Catalog watcher: %s changed", uri)
+			s.sessions.notifySubscribers(uri, JSONRPCNotification{
+				JsonRPC: "2.0",
+				Method:  "notifications/resources/updated",
+				Params:  ResourceParams{URI: uri},
+			})
+		}
+		if listChanged {
+			s.broadcast("notifications/resources/list_changed", nil)
+		}
+	}
+}
+
+func storeMarkdown(store *stores.Store) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n%s\n\n", store.Name, store.Description)
+	fmt.Fprintf(&b, "- **Website:** %s\n", store.URL)
+	fmt.Fprintf(&b, "- **Categories:** %s\n", strings.Join(store.Categories, ", "))
+	fmt.Fprintf(&b, "- **Parent company:** %s\n", store.ParentCompany)
+	fmt.Fprintf(&b, "- **Founded:** %d\n", store.FoundedYear)
+	fmt.Fprintf(&b, "- **Headquarters:** %s\n", store.HQCity)
+	fmt.Fprintf(&b, "- **Payment methods:** %s\n", strings.Join(store.PaymentMethods, ", "))
+	fmt.Fprintf(&b, "- **Delivers to:** %s\n", strings.Join(store.DeliveryRegions, ", "))
+	fmt.Fprintf(&b, "- **Cash on delivery:** %s\n", yesNo(store.CODAvailable))
+	fmt.Fprintf(&b, "- **Returns:** %s\n", store.ReturnPolicy)
+	return b.String()
+}
+
+func policyMarkdown(store *stores.Store, kind string) string {
+	var body string
+	switch kind {
+	case "returns":
+		body = store.ReturnPolicy
+	case "payments":
+		body = "Accepted payment methods: " + strings.Join(store.PaymentMethods, ", ") + "."
+	case "delivery":
+		body = "Delivers to: " + strings.Join(store.DeliveryRegions, ", ") + "."
+	case "cod":
+		if store.CODAvailable {
+			body = "Cash on delivery is available."
+		} else {
+			body = "Cash on delivery is not available; pay online at checkout."
+		}
+	}
+	return fmt.Sprintf("# %s %s policy\n\n%s\n", store.Name, kind, body)
+}
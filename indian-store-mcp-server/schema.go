@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FieldError describes one argument that failed schema validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ToolArgs are tools/call arguments after validation: defaults are filled
+// in, "integer" values are int and "number" values are float64
+type ToolArgs map[string]interface{}
+
+// Has reports whether the argument was supplied or defaulted
+func (a ToolArgs) Has(name string) bool {
+	_, ok := a[name]
+	return ok
+}
+
+func (a ToolArgs) String(name string) string {
+	v, _ := a[name].(string)
+	return v
+}
+
+func (a ToolArgs) Int(name string) int {
+	v, _ := a[name].(int)
+	return v
+}
+
+func (a ToolArgs) Float(name string) float64 {
+	v, _ := a[name].(float64)
+	return v
+}
+
+func (a ToolArgs) Bool(name string) bool {
+	v, _ := a[name].(bool)
+	return v
+}
+
+// Strings returns an array argument whose items are strings
+func (a ToolArgs) Strings(name string) []string {
+	items, _ := a[name].([]interface{})
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Object returns a nested object argument
+func (a ToolArgs) Object(name string) ToolArgs {
+	v, _ := a[name].(map[string]interface{})
+	return ToolArgs(v)
+}
+
+var patternCache sync.Map // pattern -> *regexp.Regexp
+
+// validateArguments checks args against a tool's InputSchema. It returns
+// the normalized arguments, or every field error found.
+func validateArguments(schema InputSchema, args map[string]interface{}) (ToolArgs, []FieldError) {
+	root := Property{
+		Type:                 "object",
+		Properties:           schema.Properties,
+		Required:             schema.Required,
+		AdditionalProperties: schema.AdditionalProperties,
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+
+	var errs []FieldError
+	normalized := validateValue("", root, args, &errs)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return ToolArgs(normalized.(map[string]interface{})), nil
+}
+
+func validateValue(path string, prop Property, value interface{}, errs *[]FieldError) interface{} {
+	fail := func(rule, format string, a ...interface{}) {
+		field := path
+		if field == "" {
+			field = "(arguments)"
+		}
+		*errs = append(*errs, FieldError{Field: field, Rule: rule, Message: fmt.Sprintf(format, a...)})
+	}
+
+	switch prop.Type {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			fail("type", "expected string, got %s", jsonTypeName(value))
+			return nil
+		}
+		if prop.MinLength != nil && len([]rune(s)) < *prop.MinLength {
+			fail("minLength", "must be at least %d characters", *prop.MinLength)
+		}
+		if prop.MaxLength != nil && len([]rune(s)) > *prop.MaxLength {
+			fail("maxLength", "must be at most %d characters", *prop.MaxLength)
+		}
+		if prop.Pattern != "" {
+			if re, err := compilePattern(prop.Pattern); err != nil {
+				fail("pattern", "schema pattern is invalid: %v", err)
+			} else if !re.MatchString(s) {
+				fail("pattern", "must match pattern %s", prop.Pattern)
+			}
+		}
+		if len(prop.Enum) > 0 && !containsString(prop.Enum, s) {
+			fail("enum", "must be one of: %s", strings.Join(prop.Enum, ", "))
+		}
+		return s
+
+	case "integer", "number":
+		n, ok := toFloat(value)
+		if !ok {
+			fail("type", "expected %s, got %s", prop.Type, jsonTypeName(value))
+			return nil
+		}
+		if prop.Type == "integer" && n != math.Trunc(n) {
+			fail("type", "expected integer, got %v", n)
+			return nil
+		}
+		if prop.Minimum != nil && n < *prop.Minimum {
+			fail("minimum", "must be >= %v", *prop.Minimum)
+		}
+		if prop.Maximum != nil && n > *prop.Maximum {
+			fail("maximum", "must be <= %v", *prop.Maximum)
+		}
+		if prop.Type == "integer" {
+			return int(n)
+		}
+		return n
+
+	case "boolean":
+		b, ok := value.(bool)
+		if !ok {
+			fail("type", "expected boolean, got %s", jsonTypeName(value))
+			return nil
+		}
+		return b
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			fail("type", "expected array, got %s", jsonTypeName(value))
+			return nil
+		}
+		if prop.MinItems != nil && len(items) < *prop.MinItems {
+			fail("minItems", "must contain at least %d items", *prop.MinItems)
+		}
+		if prop.MaxItems != nil && len(items) > *prop.MaxItems {
+			fail("maxItems", "must contain at most %d items", *prop.MaxItems)
+		}
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			if prop.Items != nil {
+				out[i] = validateValue(fmt.Sprintf("%s[%d]", path, i), *prop.Items, item, errs)
+			} else {
+				out[i] = item
+			}
+		}
+		return out
+
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			fail("type", "expected object, got %s", jsonTypeName(value))
+			return nil
+		}
+		out := make(map[string]interface{}, len(obj))
+
+		for _, name := range prop.Required {
+			if _, present := obj[name]; !present {
+				*errs = append(*errs, FieldError{Field: joinPath(path, name), Rule: "required", Message: "is required"})
+			}
+		}
+
+		// Sorted so the error list is stable between calls
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			child, known := prop.Properties[name]
+			if !known {
+				if prop.AdditionalProperties != nil && !*prop.AdditionalProperties {
+					*errs = append(*errs, FieldError{Field: joinPath(path, name), Rule: "additionalProperties", Message: "is not a recognized argument"})
+				} else {
+					out[name] = obj[name]
+				}
+				continue
+			}
+			out[name] = validateValue(joinPath(path, name), child, obj[name], errs)
+		}
+
+		for name, child := range prop.Properties {
+			if _, present := out[name]; !present && child.Default != nil {
+				out[name] = validateValue(joinPath(path, name), child, child.Default, errs)
+			}
+		}
+		return out
+
+	case "":
+		// Untyped property: accept anything
+		return value
+
+	default:
+		fail("type", "schema uses unsupported type %q", prop.Type)
+		return nil
+	}
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := patternCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+// toFloat accepts JSON numbers (float64) as well as the Go integer types
+// used for schema defaults
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64, int, int64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Helpers for building schemas with optional bounds
+func float64Ptr(v float64) *float64 { return &v }
+func intPtr(v int) *int             { return &v }
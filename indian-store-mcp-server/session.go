@@ -0,0 +1,252 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errSessionNotFound = errors.New("session not found")
+	errStreamAttached  = errors.New("a stream is already open for this session")
+)
+
+// Session is the per-client MCP state created by initialize
+type Session struct {
+	ID              string
+	Subject         string // authenticated subject the session is bound to
+	ProtocolVersion string // negotiated revision
+	ClientInfo      ClientInfo
+	Capabilities    ClientCapabilities
+	Initialized     bool // notifications/initialized received
+	CreatedAt       time.Time
+	LastSeen        time.Time
+
+	outbound      chan JSONRPCNotification // nil while no GET stream is attached
+	subscriptions map[string]bool          // resource URIs; only touched under the manager lock
+}
+
+// SessionManager tracks live MCP sessions and expires idle ones
+type SessionManager struct {
+	sessions    map[string]*Session
+	idleTimeout time.Duration
+	mu          sync.RWMutex
+}
+
+func NewSessionManager(idleTimeout time.Duration) *SessionManager {
+	return &SessionManager{
+		sessions:    make(map[string]*Session),
+		idleTimeout: idleTimeout,
+	}
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Create opens a session for subject from the client's initialize params
+func (m *SessionManager) Create(subject string, params InitializeParams, protocolVersion string) *Session {
+	now := time.Now()
+	session := &Session{
+		ID:              newSessionID(),
+		Subject:         subject,
+		ProtocolVersion: protocolVersion,
+		ClientInfo:      params.ClientInfo,
+		Capabilities:    params.Capabilities,
+		CreatedAt:       now,
+		LastSeen:        now,
+		subscriptions:   make(map[string]bool),
+	}
+
+	m.mu.Lock()
+	m.sessions[session.ID] = session
+	m.mu.Unlock()
+
+	log.Printf("MCP session opened: %s (subject: %s, client: %s %s, protocol: %s)",
+		session.ID, subject, params.ClientInfo.Name, params.ClientInfo.Version, protocolVersion)
+
+	snapshot := *session
+	return &snapshot
+}
+
+// Get returns a snapshot of the session and marks it as used. Sessions
+// owned by another subject are reported as not found.
+func (m *SessionManager) Get(id, subject string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok || session.Subject != subject {
+		return nil, errSessionNotFound
+	}
+	session.LastSeen = time.Now()
+
+	snapshot := *session
+	return &snapshot, nil
+}
+
+// MarkInitialized records that the client sent notifications/initialized
+func (m *SessionManager) MarkInitialized(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, ok := m.sessions[id]; ok {
+		session.Initialized = true
+	}
+}
+
+// Delete terminates a session on behalf of its owner
+func (m *SessionManager) Delete(id, subject string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok || session.Subject != subject {
+		return errSessionNotFound
+	}
+	m.remove(session)
+	log.Printf("MCP session terminated by client: %s", id)
+	return nil
+}
+
+// remove drops a session and ends its GET stream; callers hold m.mu
+func (m *SessionManager) remove(session *Session) {
+	delete(m.sessions, session.ID)
+	if session.outbound != nil {
+		close(session.outbound)
+		session.outbound = nil
+	}
+}
+
+// RunJanitor expires sessions idle for longer than the configured timeout
+func (m *SessionManager) RunJanitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := time.Now().Add(-m.idleTimeout)
+
+		m.mu.Lock()
+		for _, session := range m.sessions {
+			if session.LastSeen.Before(cutoff) {
+				log.Printf("MCP session expired after inactivity: %s", session.ID)
+				m.remove(session)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+// attachStream binds a GET stream to the session; only one may be open at a time
+func (m *SessionManager) attachStream(id string) (chan JSONRPCNotification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, errSessionNotFound
+	}
+	if session.outbound != nil {
+		return nil, errStreamAttached
+	}
+	session.outbound = make(chan JSONRPCNotification, sessionOutboundQueue)
+	return session.outbound, nil
+}
+
+func (m *SessionManager) detachStream(id string, ch chan JSONRPCNotification) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, ok := m.sessions[id]; ok && session.outbound == ch {
+		session.outbound = nil
+	}
+}
+
+// touch keeps a session with an open stream from expiring
+func (m *SessionManager) touch(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, ok := m.sessions[id]; ok {
+		session.LastSeen = time.Now()
+	}
+}
+
+// send queues a server-initiated message on the session's GET stream. It
+// reports false when no stream is open or the queue is full.
+func (m *SessionManager) send(id string, msg JSONRPCNotification) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	session, ok := m.sessions[id]
+	if !ok || session.outbound == nil {
+		return false
+	}
+	select {
+	case session.outbound <- msg:
+		return true
+	default:
+		log.Printf("Dropping %s for session %s: stream queue full", msg.Method, id)
+		return false
+	}
+}
+
+// broadcast sends a server-initiated message to every open GET stream
+func (m *SessionManager) broadcast(msg JSONRPCNotification) {
+	m.mu.RLock()
+	ids := make([]string, 0, len(m.sessions))
+	for id := range m.sessions {
+		ids = append(ids, id)
+	}
+	m.mu.RUnlock()
+
+	for _, id := range ids {
+		m.send(id, msg)
+	}
+}
+
+// Subscribe records a resources/subscribe for the session
+func (m *SessionManager) Subscribe(id, uri string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, ok := m.sessions[id]; ok {
+		session.subscriptions[uri] = true
+	}
+}
+
+// Unsubscribe drops a resource subscription
+func (m *SessionManager) Unsubscribe(id, uri string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, ok := m.sessions[id]; ok {
+		delete(session.subscriptions, uri)
+	}
+}
+
+// notifySubscribers sends msg to every session subscribed to uri or to a
+// resource nested under it (store://x/policies/returns changes with store://x)
+func (m *SessionManager) notifySubscribers(uri string, msg JSONRPCNotification) {
+	m.mu.RLock()
+	var ids []string
+	for id, session := range m.sessions {
+		for subscribed := range session.subscriptions {
+			if subscribed == uri || strings.HasPrefix(subscribed, uri+"/") {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, id := range ids {
+		m.send(id, msg)
+	}
+}
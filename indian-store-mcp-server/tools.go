@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"indian-store-mcp-server/internal/oauth"
+	"indian-store-mcp-server/internal/stores"
+)
+
+// listIndianStoresTool renders the store catalog as a numbered list
+type listIndianStoresTool struct {
+	catalog *stores.Catalog
+}
+
+func (t *listIndianStoresTool) Name() string {
+	return "list_indian_stores"
+}
+
+func (t *listIndianStoresTool) Description() string {
+	return "List popular Indian online stores with their services"
+}
+
+func (t *listIndianStoresTool) Scopes() []string {
+	return []string{oauth.ScopeStoresRead}
+}
+
+func (t *listIndianStoresTool) InputSchema() InputSchema {
+	return InputSchema{
+		Type:       "object",
+		Properties: map[string]Property{},
+	}
+}
+
+func (t *listIndianStoresTool) Call(ctx context.Context, args ToolArgs) (*CallToolResult, error) {
+	catalog, err := t.catalog.ListStores()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load store catalog: %w", err)
+	}
+	sendProgress(ctx, 1, 1, "Store list ready")
+
+	var b strings.Builder
+	for i, store := range catalog {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%d. %s - %s\n", i+1, store.Name, store.Description)
+		fmt.Fprintf(&b, "   %s | Categories: %s | COD: %s\n", store.URL, strings.Join(store.Categories, ", "), yesNo(store.CODAvailable))
+		fmt.Fprintf(&b, "   %s, founded %d in %s | Returns: %s", store.ParentCompany, store.FoundedYear, store.HQCity, store.ReturnPolicy)
+	}
+
+	return &CallToolResult{
+		Content: []Content{
+			{
+				Type: "text",
+				Text: b.String(),
+			},
+		},
+	}, nil
+}
+
+// searchStoresTool finds stores by text, category, location and payment options
+type searchStoresTool struct {
+	catalog *stores.Catalog
+}
+
+func (t *searchStoresTool) Name() string {
+	return "search_stores"
+}
+
+func (t *searchStoresTool) Description() string {
+	return "Search Indian online stores by keyword, category, delivery city/state, cash on delivery and UPI support. Returns ranked, paginated results."
+}
+
+func (t *searchStoresTool) Scopes() []string {
+	return []string{oauth.ScopeStoresRead}
+}
+
+func (t *searchStoresTool) InputSchema() InputSchema {
+	noExtra := false
+	return InputSchema{
+		Type: "object",
+		Properties: map[string]Property{
+			"query": {
+				Type:        "string",
+				Description: "Free-text search, e.g. \"tata electronics\" or \"sneakers\"",
+				MaxLength:   intPtr(100),
+			},
+			"category": {
+				Type:        "string",
+				Description: "Only stores selling this product category",
+				Enum:        stores.Categories,
+			},
+			"location": {
+				Type:        "string",
+				Description: "City or state the order ships to, e.g. \"Pune\" or \"Maharashtra\"",
+				MaxLength:   intPtr(60),
+			},
+			"cod": {
+				Type:        "boolean",
+				Description: "true for stores offering cash on delivery, false for stores without it",
+			},
+			"upi": {
+				Type:        "boolean",
+				Description: "true for stores accepting UPI payments",
+			},
+			"sort": {
+				Type:        "string",
+				Description: "Result order",
+				Enum:        stores.SortOrders,
+				Default:     "relevance",
+			},
+			"page": {
+				Type:        "integer",
+				Description: "Page number, starting at 1",
+				Minimum:     float64Ptr(1),
+				Default:     1,
+			},
+			"page_size": {
+				Type:        "integer",
+				Description: "Results per page",
+				Minimum:     float64Ptr(1),
+				Maximum:     float64Ptr(20),
+				Default:     5,
+			},
+		},
+		AdditionalProperties: &noExtra,
+	}
+}
+
+func (t *searchStoresTool) Call(ctx context.Context, args ToolArgs) (*CallToolResult, error) {
+	query := stores.SearchQuery{
+		Query:    args.String("query"),
+		Category: args.String("category"),
+		Location: args.String("location"),
+		Sort:     args.String("sort"),
+		Page:     args.Int("page"),
+		PageSize: args.Int("page_size"),
+	}
+	if args.Has("cod") {
+		cod := args.Bool("cod")
+		query.COD = &cod
+	}
+	if args.Has("upi") {
+		upi := args.Bool("upi")
+		query.UPI = &upi
+	}
+
+	page, err := t.catalog.Search(query)
+	if err != nil {
+		return nil, fmt.Errorf("store search failed: %w", err)
+	}
+
+	// Structured results for clients that understand them, the same JSON as
+	// text for those that do not
+	text, err := json.Marshal(page)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CallToolResult{
+		Content: []Content{
+			{
+				Type: "text",
+				Text: string(text),
+			},
+		},
+		StructuredContent: page,
+	}, nil
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"indian-store-mcp-server/internal/middleware"
+)
+
+// Streamable HTTP transport (MCP 2025-03-26 and later).
+//
+// POST /mcp carries client messages. The reply is a single JSON body unless a
+// handler emits notifications while it runs and the client accepts
+// text/event-stream, in which case the reply is upgraded to an SSE stream
+// that ends with the JSON-RPC response. GET /mcp opens a long-lived SSE
+// stream for server-initiated messages of a session.
+
+const (
+	headerSessionID       = "Mcp-Session-Id"
+	headerProtocolVersion = "Mcp-Protocol-Version"
+
+	sseKeepAliveInterval = 25 * time.Second
+	sessionOutboundQueue = 32
+	maxRequestBodySize   = 4 << 20
+)
+
+// JSONRPCNotification is a server-to-client message without an ID
+type JSONRPCNotification struct {
+	JsonRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// ProgressNotificationParams is the payload of notifications/progress
+type ProgressNotificationParams struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      float64     `json:"progress"`
+	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
+}
+
+// RequestMeta carries the _meta object clients may attach to request params
+type RequestMeta struct {
+	ProgressToken interface{} `json:"progressToken,omitempty"`
+}
+
+// sseWriter writes JSON-RPC messages as Server-Sent Events
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	return &sseWriter{w: w, flusher: flusher}, true
+}
+
+// start writes the event-stream headers; anything set on the response
+// header map beforehand (e.g. Mcp-Session-Id) is sent along with them
+func (s *sseWriter) start() {
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.Header().Set("Connection", "keep-alive")
+	s.w.Header().Set("X-Accel-Buffering", "no")
+	s.w.WriteHeader(http.StatusOK)
+	s.flusher.Flush()
+}
+
+func (s *sseWriter) writeEvent(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: message\ndata: %s\n\n", data); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *sseWriter) writeComment(text string) error {
+	if _, err := fmt.Fprintf(s.w, ": %s\n\n", text); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// responseStream delivers everything produced while handling one POST. It is
+// safe for concurrent use by the workers of a batch.
+type responseStream struct {
+	w         http.ResponseWriter
+	acceptSSE bool
+	sse       *sseWriter
+	mu        sync.Mutex
+}
+
+func newResponseStream(w http.ResponseWriter, r *http.Request) *responseStream {
+	return &responseStream{
+		w:         w,
+		acceptSSE: strings.Contains(r.Header.Get("Accept"), "text/event-stream"),
+	}
+}
+
+// notify sends a notification tied to the in-flight request, upgrading the
+// response to SSE on first use. Clients that only accept JSON never see
+// request-scoped notifications.
+func (rs *responseStream) notify(msg JSONRPCNotification) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if !rs.acceptSSE {
+		return
+	}
+	if rs.sse == nil {
+		sse, ok := newSSEWriter(rs.w)
+		if !ok {
+			rs.acceptSSE = false
+			return
+		}
+		sse.start()
+		rs.sse = sse
+	}
+	if err := rs.sse.writeEvent(msg); err != nil {
+		log.Printf("Failed to write SSE notification: %v", err)
+	}
+}
+
+// finish writes the final JSON-RPC response(s)
+func (rs *responseStream) finish(body interface{}) {
+	rs.finishWithStatus(body, http.StatusOK)
+}
+
+// finishWithStatus is finish with a non-200 status for plain JSON replies.
+// Once the reply has become an SSE stream the status is already sent.
+func (rs *responseStream) finishWithStatus(body interface{}, status int) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.sse != nil {
+		if err := rs.sse.writeEvent(body); err != nil {
+			log.Printf("Failed to write SSE response: %v", err)
+		}
+		return
+	}
+	rs.w.Header().Set("Content-Type", "application/json")
+	rs.w.WriteHeader(status)
+	json.NewEncoder(rs.w).Encode(body)
+}
+
+// requestScope is the per-HTTP-request state handlers reach through ctx
+type requestScope struct {
+	session   *Session                  // resolved from Mcp-Session-Id; nil before initialize
+	notify    func(JSONRPCNotification) // request-scoped notification sink
+	createdID string                    // session opened by initialize
+}
+
+type scopeKey struct{}
+
+func withScope(ctx context.Context, scope *requestScope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+func scopeFromContext(ctx context.Context) *requestScope {
+	if scope, ok := ctx.Value(scopeKey{}).(*requestScope); ok {
+		return scope
+	}
+	return &requestScope{}
+}
+
+// sessionFromContext returns the MCP session of the current request, if any
+func sessionFromContext(ctx context.Context) *Session {
+	return scopeFromContext(ctx).session
+}
+
+// notify sends a notification on the stream of the request that ctx belongs to
+func notify(ctx context.Context, method string, params interface{}) {
+	scope := scopeFromContext(ctx)
+	if scope.notify == nil {
+		return
+	}
+	scope.notify(JSONRPCNotification{JsonRPC: "2.0", Method: method, Params: params})
+}
+
+type metaKey struct{}
+
+// withRequestMeta makes the _meta of the current request available to handlers
+func withRequestMeta(ctx context.Context, meta *RequestMeta) context.Context {
+	return context.WithValue(ctx, metaKey{}, meta)
+}
+
+// sendProgress reports progress for a request that supplied a progressToken
+func sendProgress(ctx context.Context, progress, total float64, message string) {
+	meta, _ := ctx.Value(metaKey{}).(*RequestMeta)
+	if meta == nil || meta.ProgressToken == nil {
+		return
+	}
+	notify(ctx, "notifications/progress", ProgressNotificationParams{
+		ProgressToken: meta.ProgressToken,
+		Progress:      progress,
+		Total:         total,
+		Message:       message,
+	})
+}
+
+// broadcast sends a server-initiated message to every open GET stream
+func (s *MCPServer) broadcast(method string, params interface{}) {
+	s.sessions.broadcast(JSONRPCNotification{JsonRPC: "2.0", Method: method, Params: params})
+}
+
+// handleMCPStream serves GET /mcp: an SSE stream of server-initiated messages
+func (s *MCPServer) handleMCPStream(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		http.Error(w, "GET requires Accept: text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+
+	sessionID := r.Header.Get(headerSessionID)
+	if sessionID == "" {
+		http.Error(w, "Missing "+headerSessionID+" header", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.sessions.Get(sessionID, middleware.SubjectFromContext(r.Context())); err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	sse, ok := newSSEWriter(w)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	outbound, err := s.sessions.attachStream(sessionID)
+	if err != nil {
+		if errors.Is(err, errStreamAttached) {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else {
+			http.Error(w, "Session not found", http.StatusNotFound)
+		}
+		return
+	}
+	defer s.sessions.detachStream(sessionID, outbound)
+
+	log.Printf("SSE stream opened for session %s", sessionID)
+	sse.start()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			log.Printf("SSE stream closed by client for session %s", sessionID)
+			return
+		case msg, ok := <-outbound:
+			if !ok {
+				log.Printf("SSE stream closed because session %s ended", sessionID)
+				return
+			}
+			if err := sse.writeEvent(msg); err != nil {
+				log.Printf("SSE write failed for session %s: %v", sessionID, err)
+				return
+			}
+		case <-keepAlive.C:
+			if err := sse.writeComment("keep-alive"); err != nil {
+				return
+			}
+			s.sessions.touch(sessionID)
+		}
+	}
+}
+
+// handleMCPDelete serves DELETE /mcp: explicit session termination
+func (s *MCPServer) handleMCPDelete(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.Header.Get(headerSessionID)
+	if sessionID == "" {
+		http.Error(w, "Missing "+headerSessionID+" header", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.sessions.Delete(sessionID, middleware.SubjectFromContext(r.Context())); err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isSupportedProtocolVersion reports whether v is a protocol revision this server speaks
+func isSupportedProtocolVersion(v string) bool {
+	for _, supported := range supportedProtocolVersions {
+		if v == supported {
+			return true
+		}
+	}
+	return false
+}